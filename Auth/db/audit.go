@@ -0,0 +1,35 @@
+package db
+
+import (
+	"log"
+	"time"
+
+	"Auth/models"
+
+	"github.com/gocql/gocql"
+)
+
+// RecordAudit appends an admin action to the audit log. Failures are logged, not
+// returned, so auditing never blocks the action itself.
+func RecordAudit(actor, action, target, ip string, details map[string]string) {
+	now := time.Now().UTC()
+	err := Session.Query(`INSERT INTO audit_log (day, id, actor, action, target, ip, details) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		now.Format("2006-01-02"), gocql.UUIDFromTime(now), actor, action, target, ip, details).Exec()
+	if err != nil {
+		log.Printf("❌ Failed to write audit entry %s %s by %s: %v", action, target, actor, err)
+	}
+}
+
+// ListAudit returns the audit entries of one UTC day, newest first
+func ListAudit(day string, limit int) ([]models.AuditEntry, error) {
+	iter := Session.Query(`SELECT id, actor, action, target, ip, details FROM audit_log WHERE day = ? LIMIT ?`, day, limit).Iter()
+
+	entries := []models.AuditEntry{}
+	var e models.AuditEntry
+	for iter.Scan(&e.ID, &e.Actor, &e.Action, &e.Target, &e.IP, &e.Details) {
+		e.At = e.ID.Time()
+		entries = append(entries, e)
+		e = models.AuditEntry{}
+	}
+	return entries, iter.Close()
+}
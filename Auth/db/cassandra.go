@@ -3,22 +3,21 @@ package db
 import (
 	"fmt"
 	"log"
-	"os"
+	"shared/store"
 	"github.com/gocql/gocql"
 )
 
 var Session *gocql.Session
 
-func ConnectCassandra() {
-	host := os.Getenv("CASSANDRA_HOST")
-	keyspace := os.Getenv("CASSANDRA_KEYSPACE")
+// Keyspace is the keyspace the session is bound to
+var Keyspace string
 
-	cluster := gocql.NewCluster(host)
-	cluster.Keyspace = keyspace
-	cluster.Consistency = gocql.Quorum
+func ConnectCassandra() {
+	opts := store.CassandraOptionsFromEnv("auth")
+	Keyspace = opts.Keyspace
 
 	var err error
-	Session, err = cluster.CreateSession()
+	Session, err = store.ConnectCassandra(opts)
 	if err != nil {
 		log.Fatal("❌ Failed to connect to Cassandra:", err)
 	}
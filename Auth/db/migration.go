@@ -5,6 +5,8 @@ import (
 	"log"
 	"time"
 
+	"shared/store"
+
 	"github.com/gocql/gocql"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -21,15 +23,98 @@ func CreateUserTable() {
 		isverified BOOLEAN,
 		isloggedin BOOLEAN,
 		verified_at TIMESTAMP,
-		created_at TIMESTAMP
+		created_at TIMESTAMP,
+		mfa_enabled BOOLEAN,
+		mfa_secret TEXT,
+		mfa_recovery_codes SET<TEXT>,
+		disabled BOOLEAN,
+		disabled_at TIMESTAMP
 	);`
 
 	if err := Session.Query(query).Exec(); err != nil {
 		log.Fatal("❌ Error creating users table: ", err)
 	}
+
+	// Tables created before 2FA existed lack these columns
+	ensureColumns("users", [][2]string{
+		{"mfa_enabled", "BOOLEAN"},
+		{"mfa_secret", "TEXT"},
+		{"mfa_recovery_codes", "SET<TEXT>"},
+		{"disabled", "BOOLEAN"},
+		{"disabled_at", "TIMESTAMP"},
+	})
+
+	// Lets admins (and other services) look users up by id
+	if err := Session.Query(`CREATE INDEX IF NOT EXISTS users_id_idx ON users (id)`).Exec(); err != nil {
+		log.Fatal("❌ Error creating users id index: ", err)
+	}
 	fmt.Println("✅ Users table is ready")
 }
 
+// CreateSessionTable creates the per-device sessions table if it doesn't exist
+func CreateSessionTable() {
+	query := `
+	CREATE TABLE IF NOT EXISTS sessions (
+		user_id UUID,
+		session_id UUID,
+		email TEXT,
+		refresh_hash TEXT,
+		previous_refresh_hash TEXT,
+		device TEXT,
+		user_agent TEXT,
+		ip TEXT,
+		created_at TIMESTAMP,
+		last_used_at TIMESTAMP,
+		expires_at TIMESTAMP,
+		revoked BOOLEAN,
+		mfa BOOLEAN,
+		PRIMARY KEY (user_id, session_id)
+	);`
+
+	if err := Session.Query(query).Exec(); err != nil {
+		log.Fatal("❌ Error creating sessions table: ", err)
+	}
+	ensureColumns("sessions", [][2]string{{"mfa", "BOOLEAN"}, {"previous_refresh_hash", "TEXT"}})
+	fmt.Println("✅ Sessions table is ready")
+}
+
+// CreateIdentityTable creates the table linking external OAuth identities to users
+func CreateIdentityTable() {
+	query := `
+	CREATE TABLE IF NOT EXISTS user_identities (
+		provider TEXT,
+		subject TEXT,
+		email TEXT,
+		linked_at TIMESTAMP,
+		PRIMARY KEY ((provider, subject))
+	);`
+
+	if err := Session.Query(query).Exec(); err != nil {
+		log.Fatal("❌ Error creating user_identities table: ", err)
+	}
+	fmt.Println("✅ User identities table is ready")
+}
+
+// CreateAuditTable creates the audit log of admin actions, partitioned by day
+func CreateAuditTable() {
+	query := `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		day TEXT,
+		id TIMEUUID,
+		actor TEXT,
+		action TEXT,
+		target TEXT,
+		ip TEXT,
+		details MAP<TEXT, TEXT>,
+		PRIMARY KEY (day, id)
+	) WITH CLUSTERING ORDER BY (id DESC);`
+
+	if err := Session.Query(query).Exec(); err != nil {
+		log.Fatal("❌ Error creating audit_log table: ", err)
+	}
+	fmt.Println("✅ Audit log table is ready")
+}
+
 // BootstrapAdmin creates a default admin with hashed password if it doesn't exist
 func BootstrapAdmin() {
 	var id gocql.UUID
@@ -71,3 +156,14 @@ func BootstrapAdmin() {
 		fmt.Println("ℹ️ Admin already exists")
 	}
 }
+
+// ensureColumns adds the given (name, type) columns to table when they are missing
+func ensureColumns(table string, columns [][2]string) {
+	added, err := store.EnsureColumns(Session, Keyspace, table, columns)
+	for _, name := range added {
+		fmt.Printf("✅ Added column %s.%s\n", table, name)
+	}
+	if err != nil {
+		log.Fatal("❌ Error migrating schema: ", err)
+	}
+}
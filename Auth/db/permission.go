@@ -0,0 +1,130 @@
+package db
+
+import (
+	"fmt"
+	"log"
+
+	"Auth/utils"
+
+	"github.com/gocql/gocql"
+)
+
+// DefaultRolePermissions seeds role_permissions the first time it is created
+var DefaultRolePermissions = map[string][]string{
+	"admin":      {"*"},
+	"supervisor": {"camera:view:*", "camera:ptz:*", "chat:read", "chat:write", "chat:moderate", "inspection:read", "users:read"},
+	"staff":      {"chat:read", "chat:write"},
+	"viewer":     {"chat:read"},
+}
+
+// CreateRolePermissionTable creates the role -> permission mapping and seeds the
+// default roles when the table is empty. Redis must be connected first.
+func CreateRolePermissionTable() {
+	query := `
+	CREATE TABLE IF NOT EXISTS role_permissions (
+		role TEXT,
+		permission TEXT,
+		PRIMARY KEY (role, permission)
+	);`
+
+	if err := Session.Query(query).Exec(); err != nil {
+		log.Fatal("❌ Error creating role_permissions table: ", err)
+	}
+
+	var role string
+	err := Session.Query(`SELECT role FROM role_permissions LIMIT 1`).Scan(&role)
+	if err == gocql.ErrNotFound {
+		for role, perms := range DefaultRolePermissions {
+			if err := SetRolePermissions(role, perms); err != nil {
+				log.Fatalf("❌ Error seeding role %s: %v", role, err)
+			}
+		}
+		fmt.Println("✅ Default roles seeded")
+	} else if err != nil {
+		log.Fatal("❌ Error reading role_permissions: ", err)
+	}
+	fmt.Println("✅ Role permissions table is ready")
+}
+
+// RolePermissions returns the permissions of role, served from Redis when cached.
+// Unknown roles have no permissions.
+func RolePermissions(role string) ([]string, error) {
+	if perms, ok := utils.CachedRolePermissions(role); ok {
+		return perms, nil
+	}
+
+	perms := []string{}
+	iter := Session.Query(`SELECT permission FROM role_permissions WHERE role = ?`, role).Iter()
+	var p string
+	for iter.Scan(&p) {
+		perms = append(perms, p)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := utils.CacheRolePermissions(role, perms); err != nil {
+		log.Printf("⚠️ Failed to cache permissions of role %s: %v", role, err)
+	}
+	return perms, nil
+}
+
+// ListRoles returns every role with its permissions
+func ListRoles() (map[string][]string, error) {
+	roles := make(map[string][]string)
+	iter := Session.Query(`SELECT role, permission FROM role_permissions`).Iter()
+	var role, p string
+	for iter.Scan(&role, &p) {
+		roles[role] = append(roles[role], p)
+	}
+	return roles, iter.Close()
+}
+
+// RoleExists reports whether role has at least one permission
+func RoleExists(role string) (bool, error) {
+	var p string
+	err := Session.Query(`SELECT permission FROM role_permissions WHERE role = ? LIMIT 1`, role).Scan(&p)
+	if err == gocql.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// SetRolePermissions replaces the permissions of role. An empty list removes the role.
+func SetRolePermissions(role string, perms []string) error {
+	current := make(map[string]bool)
+	iter := Session.Query(`SELECT permission FROM role_permissions WHERE role = ?`, role).Iter()
+	var p string
+	for iter.Scan(&p) {
+		current[p] = true
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	// Rows of one batch share a timestamp, so a partition delete would shadow the
+	// inserts; only the difference is written instead
+	batch := Session.NewBatch(gocql.LoggedBatch)
+	wanted := make(map[string]bool)
+	for _, p := range perms {
+		wanted[p] = true
+		if !current[p] {
+			batch.Query(`INSERT INTO role_permissions (role, permission) VALUES (?, ?)`, role, p)
+		}
+	}
+	for p := range current {
+		if !wanted[p] {
+			batch.Query(`DELETE FROM role_permissions WHERE role = ? AND permission = ?`, role, p)
+		}
+	}
+	if batch.Size() > 0 {
+		if err := Session.ExecuteBatch(batch); err != nil {
+			return err
+		}
+	}
+
+	if err := utils.InvalidateRolePermissions(role); err != nil {
+		log.Printf("⚠️ Failed to invalidate cached permissions of role %s: %v", role, err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"time"
+
+	"Auth/models"
+	"Auth/utils"
+
+	"github.com/gocql/gocql"
+)
+
+// CreateSession stores a new session. The row expires with the refresh token.
+func CreateSession(s *models.Session) error {
+	return Session.Query(`
+		INSERT INTO sessions (user_id, session_id, email, refresh_hash, device, user_agent, ip, created_at, last_used_at, expires_at, revoked, mfa)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) USING TTL ?`,
+		s.UserID, s.ID, s.Email, s.RefreshHash, s.Device, s.UserAgent, s.IP,
+		s.CreatedAt, s.LastUsedAt, s.ExpiresAt, s.Revoked, s.MFA, ttlUntil(s.ExpiresAt),
+	).Exec()
+}
+
+// GetSession loads a single session of a user
+func GetSession(userID, sessionID gocql.UUID) (*models.Session, error) {
+	s := &models.Session{UserID: userID, ID: sessionID}
+	err := Session.Query(`
+		SELECT email, refresh_hash, previous_refresh_hash, device, user_agent, ip, created_at, last_used_at, expires_at, revoked, mfa
+		FROM sessions WHERE user_id = ? AND session_id = ?`, userID, sessionID).
+		Consistency(gocql.One).
+		Scan(&s.Email, &s.RefreshHash, &s.PreviousRefreshHash, &s.Device, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt, &s.Revoked, &s.MFA)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ListSessions returns every non-expired session of a user, revoked ones included
+func ListSessions(userID gocql.UUID) ([]models.Session, error) {
+	iter := Session.Query(`
+		SELECT session_id, email, device, user_agent, ip, created_at, last_used_at, expires_at, revoked, mfa
+		FROM sessions WHERE user_id = ?`, userID).Iter()
+
+	var sessions []models.Session
+	s := models.Session{UserID: userID}
+	for iter.Scan(&s.ID, &s.Email, &s.Device, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt, &s.Revoked, &s.MFA) {
+		sessions = append(sessions, s)
+	}
+	return sessions, iter.Close()
+}
+
+// RotateSession swaps the refresh hash only if the caller presented the current one.
+// It returns false when another request already rotated (or revoked) the session.
+func RotateSession(s *models.Session, oldHash, newHash, ip, userAgent string) (bool, error) {
+	now := time.Now()
+	applied, err := Session.Query(`
+		UPDATE sessions USING TTL ?
+		SET refresh_hash = ?, previous_refresh_hash = ?, last_used_at = ?, ip = ?, user_agent = ?
+		WHERE user_id = ? AND session_id = ?
+		IF refresh_hash = ? AND revoked = ?`,
+		ttlUntil(s.ExpiresAt), newHash, oldHash, now, ip, userAgent, s.UserID, s.ID, oldHash, false,
+	).MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		return false, err
+	}
+	if applied {
+		s.PreviousRefreshHash = oldHash
+		s.RefreshHash = newHash
+		s.LastUsedAt = now
+		s.IP = ip
+		s.UserAgent = userAgent
+	}
+	return applied, nil
+}
+
+// RevokeSession marks a single session as revoked so its refresh token stops working
+func RevokeSession(userID, sessionID gocql.UUID) error {
+	if err := Session.Query(`UPDATE sessions SET revoked = ? WHERE user_id = ? AND session_id = ? IF EXISTS`,
+		true, userID, sessionID).Exec(); err != nil {
+		return err
+	}
+	// Access tokens of the session are rejected right away, not when they expire
+	return utils.RevokeSessionTokens(sessionID.String())
+}
+
+// RevokeUserSessions revokes every session of a user except the ones listed in keep
+func RevokeUserSessions(userID gocql.UUID, keep ...gocql.UUID) error {
+	sessions, err := ListSessions(userID)
+	if err != nil {
+		return err
+	}
+	for _, id := range SessionsToRevoke(sessions, keep...) {
+		if err := RevokeSession(userID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SessionsToRevoke returns the ids of the sessions still active, except the
+// ones listed in keep
+func SessionsToRevoke(sessions []models.Session, keep ...gocql.UUID) []gocql.UUID {
+	var ids []gocql.UUID
+	for _, s := range sessions {
+		if s.Revoked || containsUUID(keep, s.ID) {
+			continue
+		}
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+// IsReusedRefresh reports whether hash is the refresh token the session was
+// last rotated away from. Any other wrong hash is a guess, not a replay.
+func IsReusedRefresh(s *models.Session, hash string) bool {
+	return s.PreviousRefreshHash != "" && hash == s.PreviousRefreshHash
+}
+
+// HasActiveSessions reports whether the user is still signed in on any device
+func HasActiveSessions(userID gocql.UUID) (bool, error) {
+	sessions, err := ListSessions(userID)
+	if err != nil {
+		return false, err
+	}
+	for _, s := range sessions {
+		if !s.Revoked && s.ExpiresAt.After(time.Now()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ttlUntil converts an absolute expiry into a Cassandra TTL in seconds (minimum 1)
+func ttlUntil(t time.Time) int {
+	ttl := int(time.Until(t).Seconds())
+	if ttl < 1 {
+		return 1
+	}
+	return ttl
+}
+
+func containsUUID(ids []gocql.UUID, id gocql.UUID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
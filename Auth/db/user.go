@@ -0,0 +1,131 @@
+package db
+
+import (
+	"strings"
+	"time"
+
+	"Auth/models"
+	"Auth/utils"
+
+	"github.com/gocql/gocql"
+)
+
+// userSummaryColumns is the column list scanned by scanUserSummary
+const userSummaryColumns = `id, name, email, role, isverified, isloggedin, mfa_enabled, disabled, verified_at, disabled_at, created_at`
+
+func scanUserSummary(scan func(...interface{}) bool, u *models.UserSummary) bool {
+	return scan(&u.ID, &u.Name, &u.Email, &u.Role, &u.IsVerified, &u.IsLoggedIn, &u.MFAEnabled, &u.Disabled, &u.VerifiedAt, &u.DisabledAt, &u.CreatedAt)
+}
+
+// FindUserEmail returns the stored email of the account with email, whatever
+// its case. Accounts created before emails were normalised are also found by
+// the exact email they were created with. It returns gocql.ErrNotFound when
+// there is no such account.
+func FindUserEmail(email string) (string, error) {
+	candidates := []string{utils.NormalizeEmail(email)}
+	if email != candidates[0] {
+		candidates = append(candidates, email)
+	}
+	for _, candidate := range candidates {
+		var stored string
+		err := Session.Query(`SELECT email FROM users WHERE email = ? LIMIT 1`, candidate).
+			Consistency(gocql.One).Scan(&stored)
+		if err != gocql.ErrNotFound {
+			return stored, err
+		}
+	}
+	return "", gocql.ErrNotFound
+}
+
+// GetUserByEmail loads the admin view of a user, matching email the way
+// FindUserEmail does. Writes must use the Email of the result.
+func GetUserByEmail(email string) (*models.UserSummary, error) {
+	stored, err := FindUserEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	iter := Session.Query(`SELECT `+userSummaryColumns+` FROM users WHERE email = ? LIMIT 1`, stored).
+		Consistency(gocql.One).Iter()
+	return firstUser(iter)
+}
+
+// GetUserByID loads a user through the users_id_idx secondary index
+func GetUserByID(id gocql.UUID) (*models.UserSummary, error) {
+	iter := Session.Query(`SELECT `+userSummaryColumns+` FROM users WHERE id = ? LIMIT 1`, id).
+		Consistency(gocql.One).Iter()
+	return firstUser(iter)
+}
+
+// UserFilter narrows ListUsers. Nil or empty fields mean "any".
+type UserFilter struct {
+	Role        string
+	Verified    *bool
+	Disabled    *bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
+
+func (f UserFilter) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if f.Role != "" {
+		conds = append(conds, "role = ?")
+		args = append(args, f.Role)
+	}
+	if f.Verified != nil {
+		conds = append(conds, "isverified = ?")
+		args = append(args, *f.Verified)
+	}
+	if f.Disabled != nil {
+		conds = append(conds, "disabled = ?")
+		args = append(args, *f.Disabled)
+	}
+	if f.CreatedFrom != nil {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		conds = append(conds, "created_at <= ?")
+		args = append(args, *f.CreatedTo)
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// ListUsers returns one page of users and the paging state of the next page
+// (nil when there are no more rows). Filters run server side with ALLOW FILTERING,
+// so a page may hold fewer than pageSize rows.
+func ListUsers(filter UserFilter, pageSize int, pageState []byte) ([]models.UserSummary, []byte, error) {
+	query := `SELECT ` + userSummaryColumns + ` FROM users`
+	where, args := filter.where()
+	if where != "" {
+		query += ` WHERE ` + where + ` ALLOW FILTERING`
+	}
+
+	iter := Session.Query(query, args...).PageSize(pageSize).PageState(pageState).Iter()
+	users := make([]models.UserSummary, 0, pageSize)
+	var u models.UserSummary
+	for scanUserSummary(iter.Scan, &u) {
+		users = append(users, u)
+		u = models.UserSummary{}
+	}
+	next := iter.PageState()
+	if err := iter.Close(); err != nil {
+		return nil, nil, err
+	}
+	if len(next) == 0 {
+		next = nil
+	}
+	return users, next, nil
+}
+
+func firstUser(iter *gocql.Iter) (*models.UserSummary, error) {
+	var u models.UserSummary
+	found := scanUserSummary(iter.Scan, &u)
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, gocql.ErrNotFound
+	}
+	return &u, nil
+}
@@ -1,8 +1,18 @@
 package emailjob
 
+// Job types understood by the email worker
+const (
+	TypeWelcome       = "welcome"
+	TypePasswordReset = "password_reset"
+	TypeVerifyEmail   = "verify_email"
+	TypeAccountLocked = "account_locked"
+)
+
 type EmailJob struct {
 	To      string `json:"to"`
 	Subject string `json:"subject"`
 	Body    string `json:"body"`
 	Type    string `json:"type"`
+	Name    string `json:"name,omitempty"`
+	Link    string `json:"link,omitempty"`
 }
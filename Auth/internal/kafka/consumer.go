@@ -1,7 +1,6 @@
 package kafka
 
 import (
-	"Auth/db"
 	"Auth/internal/emailjob"
 	"Auth/utils"
 	"encoding/json"
@@ -43,7 +42,7 @@ func (h *ConsumerHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim s
 		// Retry sending email up to 3 times
 		success := false
 		for attempt := 1; attempt <= 3; attempt++ {
-			if err := utils.SendWelcomeEmail(job.To, job.Subject); err != nil {
+			if err := sendJob(job); err != nil {
 				log.Printf("[worker] Email send failed for %s (attempt %d): %v", job.To, attempt, err)
 				time.Sleep(5 * time.Second)
 			} else {
@@ -59,18 +58,29 @@ func (h *ConsumerHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim s
 			continue
 		}
 
-		// ✅ Update user in Cassandra as verified
-		if err := updateUserVerified(job.To); err != nil {
-			log.Printf("[worker] Failed to update verification status for %s: %v", job.To, err)
-		} else {
-			log.Printf("[worker] ✅ User %s marked as verified in Cassandra", job.To)
-		}
-
 		sess.MarkMessage(msg, "")
 	}
 	return nil
 }
 
+// sendJob renders and sends the email matching the job type
+func sendJob(job emailjob.EmailJob) error {
+	switch job.Type {
+	case emailjob.TypePasswordReset:
+		return utils.SendPasswordResetEmail(job.To, job.Link)
+	case emailjob.TypeVerifyEmail:
+		return utils.SendVerificationEmail(job.To, job.Link)
+	case emailjob.TypeAccountLocked:
+		return utils.SendAccountLockedEmail(job.To, job.Body)
+	default:
+		name := job.Name
+		if name == "" {
+			name = job.To
+		}
+		return utils.SendWelcomeEmail(job.To, name, job.Link)
+	}
+}
+
 // sendToDLQ sends failed messages to Dead Letter Queue
 func (h *ConsumerHandler) sendToDLQ(job emailjob.EmailJob) {
 	data, _ := json.Marshal(job)
@@ -84,13 +94,3 @@ func (h *ConsumerHandler) sendToDLQ(job emailjob.EmailJob) {
 		log.Printf("[DLQ] Message sent to DLQ topic: %s", h.dlqTopic)
 	}
 }
-
-// updateUserVerified updates user status in Cassandra
-func updateUserVerified(email string) error {
-	query := `UPDATE users SET is_verified = true WHERE email = ?`
-
-	if err := db.Session.Query(query, email).Exec(); err != nil {
-		return err
-	}
-	return nil
-}
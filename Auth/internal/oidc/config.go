@@ -0,0 +1,98 @@
+package oidc
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ProviderConfig describes one identity provider allowed to sign users in
+type ProviderConfig struct {
+	Name           string
+	Issuer         string
+	ClientID       string
+	ClientSecret   string
+	RedirectURL    string
+	Scopes         []string
+	AllowedDomains []string
+	// TrustEmail accepts the emails of an IdP that never sends email_verified
+	TrustEmail bool
+}
+
+// LoadProviderConfigs reads the provider allow-list from the environment.
+//
+//	OAUTH_PROVIDERS=company
+//	OAUTH_COMPANY_ISSUER=https://idp.example.com
+//	OAUTH_COMPANY_CLIENT_ID=...
+//	OAUTH_COMPANY_CLIENT_SECRET=...
+//	OAUTH_COMPANY_REDIRECT_URL=http://localhost:8080/api/v0/oauth/callback
+//	OAUTH_COMPANY_SCOPES=openid email profile        (optional)
+//	OAUTH_COMPANY_ALLOWED_DOMAINS=divyapacking.com   (optional)
+//	OAUTH_COMPANY_TRUST_EMAIL=true                   (optional, for IdPs without email_verified)
+//
+// Providers missing an issuer or client id are skipped.
+func LoadProviderConfigs() map[string]ProviderConfig {
+	configs := make(map[string]ProviderConfig)
+	for _, name := range splitList(os.Getenv("OAUTH_PROVIDERS")) {
+		name = strings.ToLower(name)
+		prefix := "OAUTH_" + strings.ToUpper(name) + "_"
+
+		cfg := ProviderConfig{
+			Name:           name,
+			Issuer:         strings.TrimSuffix(os.Getenv(prefix+"ISSUER"), "/"),
+			ClientID:       os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret:   os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:    os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:         strings.Fields(os.Getenv(prefix + "SCOPES")),
+			AllowedDomains: splitList(os.Getenv(prefix + "ALLOWED_DOMAINS")),
+		}
+		cfg.TrustEmail, _ = strconv.ParseBool(os.Getenv(prefix + "TRUST_EMAIL"))
+		if cfg.Issuer == "" || cfg.ClientID == "" {
+			continue
+		}
+		if len(cfg.Scopes) == 0 {
+			cfg.Scopes = []string{"openid", "email", "profile"}
+		}
+		configs[name] = cfg
+	}
+	return configs
+}
+
+// EmailVerified reports whether the email of an ID token with the given
+// email_verified claim can be trusted. Without the claim only providers
+// marked TrustEmail are.
+func (c ProviderConfig) EmailVerified(claim *bool) bool {
+	if claim == nil {
+		return c.TrustEmail
+	}
+	return *claim
+}
+
+// EmailAllowed reports whether an email belongs to one of the provider's allowed domains.
+// An empty allow-list accepts every domain.
+func (c ProviderConfig) EmailAllowed(email string) bool {
+	if len(c.AllowedDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range c.AllowedDomains {
+		if strings.ToLower(allowed) == domain {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(value string) []string {
+	var out []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"shared/ratelimit"
 	"syscall"
 	"time"
 
@@ -30,10 +31,20 @@ func main() {
 	httpsProxy := os.Getenv("HTTPS_PROXY")
 	setProxyEnv(httpProxy, httpsProxy)
 
+	// ---------------- Signing keys ----------------
+	if err := utils.LoadSigningKeys(); err != nil {
+		log.Fatalf("❌ Failed to load signing keys: %v", err)
+	}
+	utils.StartKeyReloader(time.Minute)
+	log.Println("✅ Signing keys loaded")
+
 	// ---------------- Database setup ----------------
 	db.ConnectCassandra()
 	defer db.Close()
 	db.CreateUserTable()
+	db.CreateSessionTable()
+	db.CreateIdentityTable()
+	db.CreateAuditTable()
 	db.BootstrapAdmin()
 
 	// ---------------- Redis setup ----------------
@@ -43,6 +54,12 @@ func main() {
 			_ = utils.RDB.Close()
 		}
 	}()
+	// Roles are cached and revoked tokens listed in Redis, so both are set up once it is connected
+	utils.InitVerifier()
+	db.CreateRolePermissionTable()
+
+	// ---------------- OAuth setup ----------------
+	routes.InitOAuth()
 
 	// ---------------- Kafka setup ----------------
 	brokers := []string{"localhost:9092"}
@@ -65,11 +82,15 @@ func main() {
 	// ---------------- Gin setup ----------------
 	router := gin.Default()
 
-	rateLimit := redis_rate.PerMinute(10)
-	router.Use(middleware.NewRedisUserRateLimiter(utils.RDB, rateLimit))
+	// Other services fetch the public keys from here; it is not rate limited
+	router.GET("/.well-known/jwks.json", routes.JWKS)
+
+	// Runs after AuthMiddleware on protected routes, so it counts the verified
+	// user; public routes are counted per client IP
+	rateLimit := ratelimit.PerUser(utils.RDB, redis_rate.PerMinute(10))
 
 	// Health check
-	router.GET("/health", func(c *gin.Context) {
+	router.GET("/health", rateLimit, func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  "ok",
 			"message": "Auth microservice running",
@@ -78,24 +99,52 @@ func main() {
 
 	// Public routes
 	api := router.Group("/api/v0")
+	api.Use(rateLimit)
 	{
 		api.POST("/login", routes.Login)
+		api.POST("/login/mfa", routes.LoginMFA)
 		api.GET("/oauth", routes.Oauthlogin)
+		api.GET("/oauth/callback", routes.OauthCallback)
+		api.POST("/token/refresh", routes.RefreshToken)
+		api.POST("/password/forgot", routes.ForgotPassword)
+		api.POST("/password/reset", routes.ResetPassword)
+		api.GET("/verify", routes.VerifyEmail)
+		api.POST("/verify/resend", routes.ResendVerification)
 	}
 
 	// Protected routes
 	protected := router.Group("/api/v0")
-	protected.Use(middleware.AuthMiddleware(""))
+	protected.Use(middleware.AuthMiddleware(), rateLimit)
 	{
 		protected.POST("/logout", routes.Logout)
+		protected.POST("/password/change", routes.ChangePassword)
+		protected.POST("/mfa/enroll", routes.EnrollMFA)
+		protected.POST("/mfa/confirm", routes.ConfirmMFA)
 	}
 
 	// Admin routes
 	admin := router.Group("/api/v0/admin")
-	admin.Use(middleware.AuthMiddleware("admin"))
+	admin.Use(middleware.AuthMiddleware(), rateLimit)
 	{
-		admin.POST("/users", routes.CreateUser)
-		admin.DELETE("/users/:email", routes.DeleteUser)
+		canRead := middleware.RequirePermission("users:read")
+		canWrite := middleware.RequirePermission("users:write")
+		canManageRoles := middleware.RequirePermission("roles:manage")
+
+		admin.GET("/users", canRead, routes.ListUsers)
+		admin.POST("/users", canWrite, routes.CreateUser)
+		admin.GET("/users/by-id/:id", canRead, routes.GetUserByID)
+		admin.GET("/users/:email", canRead, routes.GetUser)
+		admin.PATCH("/users/:email", canWrite, routes.UpdateUser)
+		admin.DELETE("/users/:email", canWrite, routes.DeleteUser)
+		admin.POST("/users/:email/disable", canWrite, routes.DisableUser)
+		admin.POST("/users/:email/enable", canWrite, routes.EnableUser)
+		admin.POST("/users/:email/logout", canWrite, routes.ForceLogout)
+		admin.POST("/users/:email/unlock", canWrite, routes.UnlockUser)
+		admin.POST("/users/:email/tokens/revoke", canWrite, routes.RevokeUserTokens)
+		admin.POST("/tokens/revoke", canWrite, routes.RevokeToken)
+		admin.GET("/audit", middleware.RequirePermission("audit:read"), routes.ListAuditLog)
+		admin.GET("/roles", canManageRoles, routes.ListRoles)
+		admin.PUT("/roles/:role", canManageRoles, routes.SetRolePermissions)
 	}
 
 	// ---------------- Graceful Shutdown ----------------
@@ -2,69 +2,62 @@ package middleware
 
 import (
 	"net/http"
-	"strings"
 
 	"Auth/db"
 	"Auth/utils"
+	"shared/auth"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gocql/gocql"
 )
 
-func AuthMiddleware(requiredRole string) gin.HandlerFunc {
+// AuthMiddleware authenticates the caller. Combine it with RequirePermission to
+// authorize a route.
+//
+// Logout, disabling a user and role changes revoke the affected tokens in Redis,
+// which the verifier checks, so no database read is needed here.
+func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing token"})
-			c.Abort()
-			return
-		}
-
-		token := strings.TrimPrefix(authHeader, "Bearer ")
-		claims, err := utils.ParseToken(token)
+		claims, err := utils.ParseToken(auth.BearerToken(c.Request))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
-			return
-		}
-
-		role, ok := claims["role"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
+			auth.Unauthorized(c, err)
 			return
 		}
 
-		email, ok := claims["email"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
-			return
-		}
+		c.Set(auth.ContextKey, claims)
+		c.Set("user_id", claims.UserID)
+		c.Set("session_id", claims.SessionID)
+		c.Set("mfa", claims.MFA)
+		c.Set("role", claims.Role)
+		c.Set("email", claims.Email)
+		c.Next()
+	}
+}
 
-		var isLoggedIn bool
-		var userRole string
-		if err := db.Session.Query(`SELECT role, isloggedin FROM users WHERE email = ? LIMIT 1`, email).Consistency(gocql.One).Scan(&userRole, &isLoggedIn); err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User validation failed"})
+// RequirePermission lets the request through only when the caller's role grants
+// permission. It must run after AuthMiddleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		perms, err := db.RolePermissions(role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load permissions"})
 			c.Abort()
 			return
 		}
 
-		if !isLoggedIn {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session ended"})
+		if !auth.HasPermission(perms, permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied", "permission": permission})
 			c.Abort()
 			return
 		}
 
-		if requiredRole == "admin" && userRole != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: admin only"})
+		// Privileged routes need a session that passed a second factor
+		if utils.MFARequiredForRole(role) && !c.GetBool("mfa") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication required", "mfa_required": true})
 			c.Abort()
 			return
 		}
 
-		c.Set("user_id", claims["user_id"])
-		c.Set("role", role)
-		c.Set("email", email)
 		c.Next()
 	}
 }
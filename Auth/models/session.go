@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Session is one signed-in device. Each session owns a single rotating refresh token.
+type Session struct {
+	UserID              gocql.UUID `json:"user_id"`
+	ID                  gocql.UUID `json:"session_id"`
+	Email               string     `json:"email"`
+	RefreshHash         string     `json:"-"`
+	PreviousRefreshHash string     `json:"-"`
+	Device              string     `json:"device"`
+	UserAgent           string     `json:"user_agent"`
+	IP                  string     `json:"ip"`
+	CreatedAt           time.Time  `json:"created_at"`
+	LastUsedAt          time.Time  `json:"last_used_at"`
+	ExpiresAt           time.Time  `json:"expires_at"`
+	Revoked             bool       `json:"revoked"`
+	MFA                 bool       `json:"mfa"`
+}
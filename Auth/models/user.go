@@ -17,3 +17,29 @@ type User struct {
 	VerifiedAt time.Time  `json:"verified_at"`
 	CreatedAt  time.Time  `json:"created_at"`
 }
+
+// UserSummary is the admin view of a user; it never carries secrets
+type UserSummary struct {
+	ID         gocql.UUID `json:"id"`
+	Name       string     `json:"name"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	IsVerified bool       `json:"isverified"`
+	IsLoggedIn bool       `json:"isloggedin"`
+	MFAEnabled bool       `json:"mfa_enabled"`
+	Disabled   bool       `json:"disabled"`
+	VerifiedAt time.Time  `json:"verified_at"`
+	DisabledAt time.Time  `json:"disabled_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// AuditEntry records one administrative action
+type AuditEntry struct {
+	ID      gocql.UUID        `json:"id"`
+	Actor   string            `json:"actor"`
+	Action  string            `json:"action"`
+	Target  string            `json:"target"`
+	IP      string            `json:"ip"`
+	Details map[string]string `json:"details"`
+	At      time.Time         `json:"at"`
+}
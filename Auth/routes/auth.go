@@ -1,7 +1,6 @@
 package routes
 
 import (
-	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -13,10 +12,11 @@ import (
 )
 
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Name     string `json:"name"`
-	IsLoggedIn bool `json:"isloggedin"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	Name       string `json:"name"`
+	Device     string `json:"device"`
+	IsLoggedIn bool   `json:"isloggedin"`
 }
 
 func Login(c *gin.Context) {
@@ -26,42 +26,79 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	if rejectBlockedLogin(c, req.Email) {
+		return
+	}
+
 	var id gocql.UUID
-	var role, hashedPassword string
+	var role, hashedPassword, name string
+	var isVerified, mfaEnabled bool
 
-	query := `SELECT id, password, role FROM users WHERE email = ? LIMIT 1`
-	if err := db.Session.Query(query, req.Email).Consistency(gocql.One).Scan(&id, &hashedPassword, &role); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+	email, err := db.FindUserEmail(req.Email)
+	if err != nil {
+		loginFailed(c, req.Email, false, "Invalid email or password")
+		return
+	}
+	query := `SELECT id, password, role, name, isverified, mfa_enabled FROM users WHERE email = ? LIMIT 1`
+	if err := db.Session.Query(query, email).Consistency(gocql.One).Scan(&id, &hashedPassword, &role, &name, &isVerified, &mfaEnabled); err != nil {
+		loginFailed(c, email, false, "Invalid email or password")
 		return
 	}
 
 	// ✅ Compare bcrypt hash properly
 	if err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		loginFailed(c, email, true, "Invalid email or password")
+		return
+	}
+	utils.ResetLoginFailures(email)
+
+	if !isVerified && emailVerificationRequired() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Email not verified", "verification_required": true})
 		return
 	}
 
-	// Update isloggedin in DB
-	if err := db.Session.Query(`UPDATE users SET isloggedin = ? WHERE email = ?`, true, req.Email).Exec(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update login status"})
+	completeLogin(c, id, email, role, name, req.Device, mfaEnabled)
+}
+
+// completeLogin finishes a first-factor login: accounts with 2FA get a challenge,
+// everyone else gets a session for this device.
+func completeLogin(c *gin.Context, id gocql.UUID, email, role, name, device string, mfaEnabled bool) {
+	if mfaEnabled {
+		challenge, err := startMFAChallenge(email, device)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor login"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "Two-factor authentication required",
+			"mfa_required": true,
+			"mfa_token":    challenge,
+		})
 		return
 	}
 
-	req.IsLoggedIn = true
-	token, err := utils.GenerateToken(id.String(), role, req.Email, req.Name)
+	// Open a session for this device (also marks the user as logged in)
+	tokens, err := issueSession(c, id, email, role, name, device, false)
+	if err == errAccountDisabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account disabled"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":    "Login successful",
-		"token":      token,
-		"role":       role,
-		"isloggedin": req.IsLoggedIn,
-	})
+	tokens["message"] = "Login successful"
+	tokens["role"] = role
+	tokens["isloggedin"] = true
+	if utils.MFARequiredForRole(role) {
+		// Admin routes stay closed until 2FA is enrolled and used at login
+		tokens["mfa_enrollment_required"] = true
+	}
+	c.JSON(http.StatusOK, tokens)
 }
 
+// Logout revokes only the session the token was issued for
 func Logout(c *gin.Context) {
 	email := c.GetString("email")
 	if email == "" {
@@ -69,17 +106,37 @@ func Logout(c *gin.Context) {
 		return
 	}
 
-	// Update isloggedin to false
-	if err := db.Session.Query(`UPDATE users SET isloggedin = ? WHERE email = ?`, false, email).Exec(); err != nil {
+	userID, err := gocql.ParseUUID(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized request"})
+		return
+	}
+	sessionID, err := gocql.ParseUUID(c.GetString("session_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized request"})
+		return
+	}
+
+	if err := db.RevokeSession(userID, sessionID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
 		return
 	}
 
+	// isloggedin stays true while any other device still holds a session
+	active, err := db.HasActiveSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		return
+	}
+	if !active {
+		if err := db.Session.Query(`UPDATE users SET isloggedin = ? WHERE email = ?`, false, email).Exec(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Logout successful",
 		"token":   "", // Return empty token
 	})
 }
-func Oauthlogin(c *gin.Context) {
-	fmt.Println("Oauth login")
-}
\ No newline at end of file
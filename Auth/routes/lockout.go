@@ -0,0 +1,93 @@
+package routes
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"Auth/internal/emailjob"
+	"Auth/internal/kafka"
+	"Auth/utils"
+)
+
+// rejectBlockedLogin answers the request when the account is locked or backing off
+func rejectBlockedLogin(c *gin.Context, email string) bool {
+	block, err := utils.LoginBlocked(email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login status"})
+		return true
+	}
+	if block.Locked {
+		c.JSON(http.StatusLocked, gin.H{
+			"error":   "Account temporarily locked",
+			"message": "Too many failed login attempts. Try again later or contact an admin.",
+			"retryIn": retrySeconds(block.RetryAfter),
+		})
+		return true
+	}
+	if block.RetryAfter > 0 {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":   "Too many failed attempts",
+			"message": "Wait before trying again.",
+			"retryIn": retrySeconds(block.RetryAfter),
+		})
+		return true
+	}
+	return false
+}
+
+// loginFailed records a failed attempt and, when it locks the account, tells the owner
+func loginFailed(c *gin.Context, email string, accountExists bool, message string) {
+	lockedFor, err := utils.RecordLoginFailure(email)
+	if err != nil {
+		log.Printf("❌ Failed to record login failure for %s: %v", email, err)
+	}
+
+	if lockedFor == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": message})
+		return
+	}
+
+	log.Printf("🔒 Account %s locked for %s after repeated failed logins from %s", email, lockedFor, c.ClientIP())
+	if accountExists {
+		until := time.Now().Add(lockedFor).UTC().Format("02 Jan 2006 15:04 MST")
+		job := emailjob.EmailJob{
+			To:      email,
+			Subject: "Your account has been locked",
+			Body:    fmt.Sprintf("Too many failed sign-in attempts were made from %s. Your account is locked until %s.", c.ClientIP(), until),
+			Type:    emailjob.TypeAccountLocked,
+		}
+		if err := kafka.PublishEmailJob(job); err != nil {
+			log.Printf("❌ Failed to queue lockout email for %s: %v", email, err)
+		}
+	}
+
+	c.JSON(http.StatusLocked, gin.H{
+		"error":   "Account temporarily locked",
+		"message": "Too many failed login attempts. Try again later or contact an admin.",
+		"retryIn": retrySeconds(lockedFor),
+	})
+}
+
+// UnlockUser lets an admin lift a lockout before it expires
+func UnlockUser(c *gin.Context) {
+	email := c.Param("email")
+
+	wasLocked, err := utils.UnlockAccount(email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock user"})
+		return
+	}
+	audit(c, "user.unlock", email, map[string]string{"was_locked": strconv.FormatBool(wasLocked)})
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked", "was_locked": wasLocked})
+}
+
+func retrySeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
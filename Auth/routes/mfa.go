@@ -0,0 +1,239 @@
+package routes
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocql/gocql"
+	"github.com/redis/go-redis/v9"
+
+	"Auth/db"
+	"Auth/utils"
+)
+
+const (
+	// mfaEnrollTTL bounds how long an unconfirmed TOTP secret is kept
+	mfaEnrollTTL = 10 * time.Minute
+	// mfaChallengeTTL bounds the time between password and second factor
+	mfaChallengeTTL = 5 * time.Minute
+	// mfaChallengeAttempts is how many wrong codes a challenge tolerates
+	mfaChallengeAttempts = 5
+	recoveryCodeCount    = 10
+)
+
+type ConfirmMFARequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type LoginMFARequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// EnrollMFA generates a TOTP secret for the signed-in user. It only becomes
+// active once a code from the authenticator app is confirmed.
+func EnrollMFA(c *gin.Context) {
+	email := c.GetString("email")
+	userID := c.GetString("user_id")
+
+	var enabled bool
+	if err := db.Session.Query(`SELECT mfa_enabled FROM users WHERE email = ? LIMIT 1`, email).
+		Consistency(gocql.One).Scan(&enabled); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User validation failed"})
+		return
+	}
+	if enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := utils.NewTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+	if err := utils.RDB.Set(utils.Ctx, "mfa:pending:"+userID, secret, mfaEnrollTTL).Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrollment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Scan the URI with an authenticator app, then confirm a code",
+		"secret":      secret,
+		"otpauth_uri": utils.OTPAuthURI(email, secret),
+		"expires_in":  int(mfaEnrollTTL.Seconds()),
+	})
+}
+
+// ConfirmMFA activates the pending secret and returns one-time recovery codes
+func ConfirmMFA(c *gin.Context) {
+	var req ConfirmMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	email := c.GetString("email")
+	userID := c.GetString("user_id")
+
+	secret, err := utils.RDB.Get(utils.Ctx, "mfa:pending:"+userID).Result()
+	if err == redis.Nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No pending enrollment, start again"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm enrollment"})
+		return
+	}
+
+	step, ok := utils.VerifyTOTP(secret, req.Code, time.Now())
+	if !ok || !claimTOTPStep(userID, step) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+	hashed := make([]string, len(codes))
+	for i, code := range codes {
+		hashed[i] = utils.HashToken(code)
+	}
+
+	if err := db.Session.Query(`UPDATE users SET mfa_enabled = ?, mfa_secret = ?, mfa_recovery_codes = ? WHERE email = ?`,
+		true, secret, hashed, email).Exec(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+	utils.RDB.Del(utils.Ctx, "mfa:pending:"+userID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled. Store the recovery codes safely, they are shown only once.",
+		"recovery_codes": codes,
+	})
+}
+
+// LoginMFA is the second login step: it trades an MFA challenge plus a TOTP or
+// recovery code for a session.
+func LoginMFA(c *gin.Context) {
+	var req LoginMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code or recovery_code is required"})
+		return
+	}
+
+	claims, err := utils.ParsePurposeToken(req.MFAToken, utils.PurposeMFAChallenge)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Two-factor challenge is invalid or has expired"})
+		return
+	}
+	email := claims["sub"].(string)
+	if rejectBlockedLogin(c, email) {
+		return
+	}
+	challengeKey := "mfa:challenge:" + utils.HashToken(req.MFAToken)
+
+	device, err := utils.RDB.Get(utils.Ctx, challengeKey).Result()
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Two-factor challenge is invalid or has expired"})
+		return
+	}
+
+	var id gocql.UUID
+	var role, name, secret string
+	var recoveryCodes []string
+	if err := db.Session.Query(`SELECT id, role, name, mfa_secret, mfa_recovery_codes FROM users WHERE email = ? LIMIT 1`, email).
+		Consistency(gocql.One).Scan(&id, &role, &name, &secret, &recoveryCodes); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User validation failed"})
+		return
+	}
+
+	var passed bool
+	if req.Code != "" {
+		step, ok := utils.VerifyTOTP(secret, req.Code, time.Now())
+		passed = ok && claimTOTPStep(id.String(), step)
+	} else {
+		passed = useRecoveryCode(email, recoveryCodes, req.RecoveryCode)
+	}
+
+	if !passed {
+		attempts, _ := utils.RDB.Incr(utils.Ctx, challengeKey+":attempts").Result()
+		utils.RDB.Expire(utils.Ctx, challengeKey+":attempts", mfaChallengeTTL)
+		if attempts >= mfaChallengeAttempts {
+			utils.RDB.Del(utils.Ctx, challengeKey, challengeKey+":attempts")
+		}
+		loginFailed(c, email, true, "Invalid two-factor code")
+		return
+	}
+	utils.ResetLoginFailures(email)
+
+	// A challenge opens exactly one session
+	if deleted, err := utils.RDB.Del(utils.Ctx, challengeKey, challengeKey+":attempts").Result(); err != nil || deleted == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Two-factor challenge is invalid or has expired"})
+		return
+	}
+
+	tokens, err := issueSession(c, id, email, role, name, device, true)
+	if err == errAccountDisabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account disabled"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+
+	tokens["message"] = "Login successful"
+	tokens["role"] = role
+	tokens["isloggedin"] = true
+	c.JSON(http.StatusOK, tokens)
+}
+
+// startMFAChallenge issues the short-lived token returned by the first login step
+func startMFAChallenge(email, device string) (string, error) {
+	token, err := utils.GeneratePurposeToken(utils.PurposeMFAChallenge, email, mfaChallengeTTL)
+	if err != nil {
+		return "", err
+	}
+	if err := utils.RDB.Set(utils.Ctx, "mfa:challenge:"+utils.HashToken(token), device, mfaChallengeTTL).Err(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// claimTOTPStep makes every TOTP code usable only once per user
+func claimTOTPStep(userID string, step int64) bool {
+	ok, err := utils.RDB.SetNX(utils.Ctx, fmt.Sprintf("mfa:used:%s:%d", userID, step), 1, 3*time.Minute).Result()
+	return err == nil && ok
+}
+
+// useRecoveryCode removes a matching recovery code so it can't be used again
+func useRecoveryCode(email string, hashedCodes []string, code string) bool {
+	hash := utils.HashToken(utils.NormalizeRecoveryCode(code))
+	for _, candidate := range hashedCodes {
+		if candidate != hash {
+			continue
+		}
+		// Guard against two concurrent logins racing on the same code
+		if claimed, err := utils.RDB.SetNX(utils.Ctx, "mfa:recovery:"+hash, 1, time.Hour).Result(); err != nil || !claimed {
+			return false
+		}
+		if err := db.Session.Query(`UPDATE users SET mfa_recovery_codes = mfa_recovery_codes - ? WHERE email = ?`,
+			[]string{hash}, email).Exec(); err != nil {
+			log.Printf("❌ Failed to consume recovery code for %s: %v", email, err)
+			return false
+		}
+		return true
+	}
+	return false
+}
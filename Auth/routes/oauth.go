@@ -0,0 +1,187 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocql/gocql"
+	"github.com/redis/go-redis/v9"
+
+	"Auth/db"
+	"Auth/internal/oidc"
+	"Auth/utils"
+)
+
+// oauthStateTTL bounds how long a user may take at the identity provider
+const oauthStateTTL = 10 * time.Minute
+
+var oauthProviders *oidc.Registry
+
+// oauthState is what we remember in Redis between the redirect and the callback
+type oauthState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	Device       string `json:"device"`
+}
+
+// InitOAuth loads the provider allow-list. Call it after the environment is loaded.
+func InitOAuth() {
+	oauthProviders = oidc.NewRegistry(oidc.LoadProviderConfigs(), nil)
+	log.Printf("✅ OAuth providers enabled: %v", oauthProviders.Names())
+}
+
+// Oauthlogin starts the authorization-code + PKCE flow and redirects to the provider
+func Oauthlogin(c *gin.Context) {
+	if oauthProviders == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "OAuth login is not configured"})
+		return
+	}
+
+	provider, err := oauthProviders.Provider(c.Request.Context(), c.Query("provider"))
+	if errors.Is(err, oidc.ErrUnknownProvider) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown or disallowed provider", "providers": oauthProviders.Names()})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ OAuth discovery failed: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider unavailable"})
+		return
+	}
+
+	state, err1 := oidc.RandomString(24)
+	nonce, err2 := oidc.RandomString(24)
+	verifier, err3 := oidc.NewCodeVerifier()
+	if err := errors.Join(err1, err2, err3); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start OAuth login"})
+		return
+	}
+
+	data, _ := json.Marshal(oauthState{
+		Provider:     provider.Config.Name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		Device:       c.Query("device"),
+	})
+	if err := utils.RDB.Set(utils.Ctx, "oauth:state:"+state, data, oauthStateTTL).Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start OAuth login"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, provider.AuthCodeURL(state, nonce, oidc.CodeChallengeS256(verifier)))
+}
+
+// OauthCallback completes the flow, links the external identity and opens a session
+func OauthCallback(c *gin.Context) {
+	if errParam := c.Query("error"); errParam != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Identity provider denied login", "reason": errParam})
+		return
+	}
+
+	stateKey := c.Query("state")
+	code := c.Query("code")
+	if stateKey == "" || code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing state or code"})
+		return
+	}
+
+	// GETDEL makes every state single-use
+	raw, err := utils.RDB.GetDel(utils.Ctx, "oauth:state:"+stateKey).Bytes()
+	if err == redis.Nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login attempt expired or already used"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate login attempt"})
+		return
+	}
+	var state oauthState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid login attempt"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	provider, err := oauthProviders.Provider(ctx, state.Provider)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider unavailable"})
+		return
+	}
+	tokens, err := provider.Exchange(ctx, code, state.CodeVerifier)
+	if err != nil {
+		log.Printf("❌ OAuth code exchange failed: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to exchange authorization code"})
+		return
+	}
+	claims, err := provider.VerifyIDToken(ctx, tokens.IDToken, state.Nonce)
+	if err != nil {
+		log.Printf("❌ OAuth id_token rejected: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid identity token"})
+		return
+	}
+
+	email := utils.NormalizeEmail(claims.Email)
+	if email == "" || !provider.Config.EmailVerified(claims.EmailVerified) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Identity provider did not return a verified email"})
+		return
+	}
+	if !provider.Config.EmailAllowed(email) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Email domain not allowed for this provider"})
+		return
+	}
+
+	email, err = linkIdentity(provider.Config.Name, claims.Subject, email)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "No account is linked to this identity"})
+		return
+	}
+
+	var id gocql.UUID
+	var role, name string
+	var mfaEnabled bool
+	if err := db.Session.Query(`SELECT id, role, name, mfa_enabled FROM users WHERE email = ? LIMIT 1`, email).
+		Consistency(gocql.One).Scan(&id, &role, &name, &mfaEnabled); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "No account is linked to this identity"})
+		return
+	}
+
+	device := state.Device
+	if device == "" {
+		device = "oauth:" + provider.Config.Name
+	}
+	completeLogin(c, id, email, role, name, device, mfaEnabled)
+}
+
+// linkIdentity returns the local email for an external identity. The first login
+// links (provider, subject) to the existing user with the same email; accounts are
+// never created here.
+func linkIdentity(provider, subject, email string) (string, error) {
+	var linked string
+	err := db.Session.Query(`SELECT email FROM user_identities WHERE provider = ? AND subject = ?`, provider, subject).
+		Consistency(gocql.One).Scan(&linked)
+	if err == nil {
+		return linked, nil
+	}
+	if err != gocql.ErrNotFound {
+		return "", err
+	}
+
+	existing, err := db.FindUserEmail(email)
+	if err != nil {
+		return "", err
+	}
+
+	if err := db.Session.Query(`INSERT INTO user_identities (provider, subject, email, linked_at) VALUES (?, ?, ?, ?)`,
+		provider, subject, existing, time.Now()).Exec(); err != nil {
+		return "", err
+	}
+	log.Printf("🔗 Linked %s identity %s to %s", provider, subject, existing)
+	return existing, nil
+}
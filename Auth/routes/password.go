@@ -0,0 +1,217 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocql/gocql"
+	"golang.org/x/crypto/bcrypt"
+
+	"Auth/db"
+	"Auth/internal/emailjob"
+	"Auth/internal/kafka"
+	"Auth/utils"
+)
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8"`
+}
+
+// PasswordStore is the storage behind the password handlers
+type PasswordStore interface {
+	UserID(email string) (gocql.UUID, error)
+	PasswordHash(email string) (string, error)
+	SetPassword(email, password string) error
+	RevokeUserSessions(userID gocql.UUID, keep ...gocql.UUID) error
+	SetLoggedOut(email string) error
+}
+
+// Passwords is the PasswordStore in use. Tests swap it out.
+var Passwords PasswordStore = cassandraPasswords{}
+
+// ForgotPassword emails a single-use reset link. It answers the same way whether
+// or not the account exists so it can't be used to discover emails.
+func ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"message": "If the account exists, a reset link has been sent"}
+
+	existing, err := db.FindUserEmail(req.Email)
+	if err != nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	token, err := utils.IssueResetToken(existing, passwordResetTTL())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reset token"})
+		return
+	}
+
+	job := emailjob.EmailJob{
+		To:      existing,
+		Subject: "Reset your password",
+		Type:    emailjob.TypePasswordReset,
+		Link:    appBaseURL() + "/reset-password?token=" + token,
+	}
+	if err := kafka.PublishEmailJob(job); err != nil {
+		log.Printf("❌ Failed to queue password reset email for %s: %v", existing, err)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ResetPassword consumes a reset token, sets the new password and signs out every device
+func ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	email, err := utils.ConsumeResetToken(req.Token)
+	if errors.Is(err, utils.ErrInvalidResetToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reset link is invalid or has expired"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	id, err := Passwords.UserID(email)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reset link is invalid or has expired"})
+		return
+	}
+
+	if err := Passwords.SetPassword(email, req.NewPassword); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	// A device left signed in would keep whoever had the old password in
+	if err := Passwords.RevokeUserSessions(id); err != nil {
+		log.Printf("❌ Failed to revoke sessions for %s after reset: %v", email, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign out other devices"})
+		return
+	}
+	if err := Passwords.SetLoggedOut(email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset, please log in again"})
+}
+
+// ChangePassword updates the password of the signed-in user and revokes their other sessions
+func ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	email := c.GetString("email")
+	userID, err := gocql.ParseUUID(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized request"})
+		return
+	}
+	sessionID, _ := gocql.ParseUUID(c.GetString("session_id"))
+
+	hashedPassword, err := Passwords.PasswordHash(email)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User validation failed"})
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(req.OldPassword)); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+	if req.OldPassword == req.NewPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New password must differ from the current one"})
+		return
+	}
+
+	if err := Passwords.SetPassword(email, req.NewPassword); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+	if err := Passwords.RevokeUserSessions(userID, sessionID); err != nil {
+		log.Printf("❌ Failed to revoke other sessions for %s: %v", email, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign out other devices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed, other devices have been signed out"})
+}
+
+// cassandraPasswords is the PasswordStore of the users and sessions tables
+type cassandraPasswords struct{}
+
+func (cassandraPasswords) UserID(email string) (gocql.UUID, error) {
+	var id gocql.UUID
+	err := db.Session.Query(`SELECT id FROM users WHERE email = ? LIMIT 1`, email).
+		Consistency(gocql.One).Scan(&id)
+	return id, err
+}
+
+func (cassandraPasswords) PasswordHash(email string) (string, error) {
+	var hashed string
+	err := db.Session.Query(`SELECT password FROM users WHERE email = ? LIMIT 1`, email).
+		Consistency(gocql.One).Scan(&hashed)
+	return hashed, err
+}
+
+func (cassandraPasswords) SetPassword(email, password string) error {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return db.Session.Query(`UPDATE users SET password = ? WHERE email = ?`, string(hashed), email).Exec()
+}
+
+func (cassandraPasswords) RevokeUserSessions(userID gocql.UUID, keep ...gocql.UUID) error {
+	return db.RevokeUserSessions(userID, keep...)
+}
+
+func (cassandraPasswords) SetLoggedOut(email string) error {
+	return db.Session.Query(`UPDATE users SET isloggedin = ? WHERE email = ?`, false, email).Exec()
+}
+
+// passwordResetTTL returns how long reset links stay valid (PASSWORD_RESET_TTL_MINUTES, default 30)
+func passwordResetTTL() time.Duration {
+	if minutes, err := strconv.Atoi(os.Getenv("PASSWORD_RESET_TTL_MINUTES")); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return 30 * time.Minute
+}
+
+// appBaseURL is the public URL used to build links in emails
+func appBaseURL() string {
+	if base := os.Getenv("APP_BASE_URL"); base != "" {
+		return strings.TrimSuffix(base, "/")
+	}
+	return "http://localhost:8080"
+}
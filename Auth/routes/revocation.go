@@ -0,0 +1,72 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"Auth/db"
+	"Auth/utils"
+	"shared/auth"
+)
+
+type RevokeTokenRequest struct {
+	Token string `json:"token"`
+	JTI   string `json:"jti"`
+}
+
+// RevokeToken revokes a single access token, given either the token itself or
+// its jti. Every service rejects it from then on.
+func RevokeToken(c *gin.Context) {
+	var req RevokeTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (req.Token == "") == (req.JTI == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide either token or jti"})
+		return
+	}
+
+	jti := req.JTI
+	if req.Token != "" {
+		claims, err := utils.ParseToken(req.Token)
+		if reason := auth.ReasonOf(err); err != nil && (reason == auth.ReasonExpired || reason == auth.ReasonRevoked) {
+			c.JSON(http.StatusOK, gin.H{"message": "Token is already unusable", "reason": reason})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token", "reason": reason})
+			return
+		}
+		if err := utils.RevokeToken(claims); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+			return
+		}
+		jti = claims.ID
+	} else if err := utils.RevokeJTI(jti); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+	audit(c, "token.revoke", jti, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked", "jti": jti})
+}
+
+// RevokeUserTokens revokes every access token issued to a user so far. Unlike
+// ForceLogout the sessions stay open, so the user's clients refresh and carry on.
+func RevokeUserTokens(c *gin.Context) {
+	user, err := db.GetUserByEmail(c.Param("email"))
+	if err != nil {
+		respondUser(c, nil, err)
+		return
+	}
+	email := user.Email
+
+	if err := utils.RevokeUserTokens(user.ID.String()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke tokens"})
+		return
+	}
+	audit(c, "user.revoke_tokens", email, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "All tokens of the user revoked"})
+}
@@ -0,0 +1,75 @@
+package routes
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"Auth/db"
+	"shared/auth"
+)
+
+type RolePermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+// ListRoles returns every role with the permissions it grants
+func ListRoles(c *gin.Context) {
+	roles, err := db.ListRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list roles"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+// SetRolePermissions replaces the permissions of a role, creating the role if
+// needed. An empty list removes it. Tokens already issued keep their old
+// permissions until they are refreshed.
+func SetRolePermissions(c *gin.Context) {
+	role := c.Param("role")
+
+	var req RolePermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, p := range req.Permissions {
+		if !auth.ValidPermission(p) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid permission", "permission": p})
+			return
+		}
+	}
+	// Locking every admin out by accident is hard to undo
+	if c.GetString("role") == role && !auth.HasPermission(req.Permissions, "roles:manage") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot remove roles:manage from your own role"})
+		return
+	}
+
+	if err := db.SetRolePermissions(role, req.Permissions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	perms := append([]string(nil), req.Permissions...)
+	sort.Strings(perms)
+	audit(c, "role.update", role, map[string]string{"permissions": strings.Join(perms, ",")})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated successfully", "role": role, "permissions": perms})
+}
+
+// validRole answers 400 and returns false when role is not defined
+func validRole(c *gin.Context, role string) bool {
+	exists, err := db.RoleExists(role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate role"})
+		return false
+	}
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role", "role": role})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,180 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocql/gocql"
+
+	"Auth/db"
+	"Auth/models"
+	"Auth/utils"
+)
+
+// errAccountDisabled is returned by issueSession for disabled users
+var errAccountDisabled = errors.New("account disabled")
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// issueSession opens a new device session and returns the token pair for it.
+// mfa records whether the login passed a second factor.
+func issueSession(c *gin.Context, userID gocql.UUID, email, role, name, device string, mfa bool) (gin.H, error) {
+	var disabled bool
+	if err := db.Session.Query(`SELECT disabled FROM users WHERE email = ? LIMIT 1`, email).
+		Consistency(gocql.One).Scan(&disabled); err != nil {
+		return nil, err
+	}
+	if disabled {
+		return nil, errAccountDisabled
+	}
+
+	secret, err := utils.NewRefreshSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		UserID:      userID,
+		ID:          gocql.TimeUUID(),
+		Email:       email,
+		RefreshHash: utils.HashToken(secret),
+		Device:      device,
+		UserAgent:   c.Request.UserAgent(),
+		IP:          c.ClientIP(),
+		CreatedAt:   now,
+		LastUsedAt:  now,
+		ExpiresAt:   now.Add(utils.RefreshTokenTTL()),
+		MFA:         mfa,
+	}
+	if err := db.CreateSession(session); err != nil {
+		return nil, err
+	}
+
+	if err := db.Session.Query(`UPDATE users SET isloggedin = ? WHERE email = ?`, true, email).Exec(); err != nil {
+		return nil, err
+	}
+
+	return tokenPair(session, role, name, secret)
+}
+
+// tokenPair signs an access token for the session and formats its refresh token
+func tokenPair(session *models.Session, role, name, secret string) (gin.H, error) {
+	perms, err := db.RolePermissions(role)
+	if err != nil {
+		return nil, err
+	}
+	perms = utils.TokenPermissions(role, perms, session.MFA)
+	accessToken, err := utils.GenerateToken(session.UserID.String(), role, session.Email, name, session.ID.String(), session.MFA, perms)
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"token":         accessToken,
+		"mfa":           session.MFA,
+		"refresh_token": utils.FormatRefreshToken(session.UserID.String(), session.ID.String(), secret),
+		"expires_in":    int(utils.AccessTokenTTL().Seconds()),
+		"session_id":    session.ID.String(),
+		"permissions":   perms,
+	}, nil
+}
+
+// RefreshToken exchanges a refresh token for a new token pair.
+// Presenting the refresh token the session was just rotated away from revokes
+// the whole session.
+func RefreshToken(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rawUserID, rawSessionID, secret, err := utils.ParseRefreshToken(req.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+	userID, err := gocql.ParseUUID(rawUserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+	sessionID, err := gocql.ParseUUID(rawSessionID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	session, err := db.GetSession(userID, sessionID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+	if session.Revoked || time.Now().After(session.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session ended"})
+		return
+	}
+
+	presentedHash := utils.HashToken(secret)
+	if presentedHash != session.RefreshHash {
+		if !db.IsReusedRefresh(session, presentedHash) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+		revokeReusedSession(session)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected"})
+		return
+	}
+
+	newSecret, err := utils.NewRefreshSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	applied, err := db.RotateSession(session, presentedHash, utils.HashToken(newSecret), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+	if !applied {
+		// Someone rotated this token between our read and write
+		revokeReusedSession(session)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected"})
+		return
+	}
+
+	var role, name string
+	var disabled bool
+	if err := db.Session.Query(`SELECT role, name, disabled FROM users WHERE email = ? LIMIT 1`, session.Email).
+		Consistency(gocql.One).Scan(&role, &name, &disabled); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User validation failed"})
+		return
+	}
+	if disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account disabled"})
+		return
+	}
+
+	tokens, err := tokenPair(session, role, name, newSecret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	tokens["message"] = "Token refreshed"
+	c.JSON(http.StatusOK, tokens)
+}
+
+func revokeReusedSession(session *models.Session) {
+	log.Printf("⚠️ Refresh token reuse for user=%s session=%s — revoking session", session.UserID, session.ID)
+	if err := db.RevokeSession(session.UserID, session.ID); err != nil {
+		log.Printf("❌ Failed to revoke session %s: %v", session.ID, err)
+	}
+}
@@ -2,12 +2,15 @@ package routes
 
 import (
 	"Auth/db"
+	"Auth/internal/emailjob"
 	"Auth/models"
+	"Auth/utils"
+	"encoding/base64"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"time"
-	"Auth/internal/kafka"
-	"Auth/internal/emailjob"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gocql/gocql"
@@ -23,10 +26,14 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
-	// 🔍 Check if email already exists
-	var existingEmail string
-	checkQuery := `SELECT email FROM users WHERE email = ? LIMIT 1`
-	err := db.Session.Query(checkQuery, user.Email).Scan(&existingEmail)
+	if !validRole(c, user.Role) {
+		return
+	}
+
+	// 🔍 Check if email already exists, in any case
+	typed := user.Email
+	user.Email = utils.NormalizeEmail(user.Email)
+	_, err := db.FindUserEmail(typed)
 	if err == nil {
 		// Means record found
 		c.JSON(http.StatusConflict, gin.H{"error": "User already exists with this email"})
@@ -43,21 +50,26 @@ func CreateUser(c *gin.Context) {
 	user.ID = gocql.TimeUUID()
 	user.CreatedAt = time.Now()
 	user.IsLoggedIn = false
+	user.IsVerified = false
 
 	// Insert new user
-	insertQuery := `INSERT INTO users (id, name, email, password, role, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+	insertQuery := `INSERT INTO users (id, name, email, password, role, isverified, isloggedin, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
 	if err := db.Session.Query(insertQuery,
-		user.ID, user.Name, user.Email, string(hashed), user.Role, user.CreatedAt).Exec(); err != nil {
+		user.ID, user.Name, user.Email, string(hashed), user.Role, user.IsVerified, user.IsLoggedIn, user.CreatedAt).Exec(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
 	emailJob := emailjob.EmailJob{
-    To:       user.Email,
-    Subject:  "Welcome to Our Platform",
-    Body:     fmt.Sprintf("Hello %s, welcome aboard!", user.Name),
-	Type:"welcome",
-}
-    kafka.PublishEmailJob(emailJob)
+		To:      user.Email,
+		Subject: "Welcome to Our Platform",
+		Body:    fmt.Sprintf("Hello %s, welcome aboard!", user.Name),
+		Type:    emailjob.TypeWelcome,
+		Name:    user.Name,
+	}
+	if err := queueVerificationEmail(emailJob); err != nil {
+		log.Printf("❌ Failed to queue welcome email for %s: %v", user.Email, err)
+	}
+	audit(c, "user.create", user.Email, map[string]string{"role": user.Role})
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "User created successfully",
 		"id":      user.ID,
@@ -65,15 +77,276 @@ func CreateUser(c *gin.Context) {
 	})
 }
 
+// DeleteUser soft-disables the account; user rows are never hard deleted
 func DeleteUser(c *gin.Context) {
-	email := c.Param("email") // get email from URL
+	setUserDisabled(c, c.Param("email"), true)
+}
+
+type UpdateUserRequest struct {
+	Name *string `json:"name"`
+	Role *string `json:"role"`
+}
+
+// ListUsers returns a page of users. Supported filters: role, verified, disabled,
+// created_from and created_to (RFC 3339). Pass next_page_state back as page_state.
+func ListUsers(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return
+	}
 
-	// Delete user by email (primary key)
-	query := `DELETE FROM users WHERE email = ?`
-	if err := db.Session.Query(query, email).Exec(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+	var filter db.UserFilter
+	filter.Role = c.Query("role")
+	if filter.Verified, err = optionalBool(c.Query("verified")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "verified must be true or false"})
 		return
 	}
+	if filter.Disabled, err = optionalBool(c.Query("disabled")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "disabled must be true or false"})
+		return
+	}
+	if filter.CreatedFrom, err = optionalTime(c.Query("created_from")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "created_from must be an RFC 3339 timestamp"})
+		return
+	}
+	if filter.CreatedTo, err = optionalTime(c.Query("created_to")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "created_to must be an RFC 3339 timestamp"})
+		return
+	}
+
+	var pageState []byte
+	if raw := c.Query("page_state"); raw != "" {
+		if pageState, err = base64.RawURLEncoding.DecodeString(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page_state"})
+			return
+		}
+	}
+
+	users, next, err := db.ListUsers(filter, limit, pageState)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list users"})
+		return
+	}
+
+	response := gin.H{"users": users, "count": len(users)}
+	if next != nil {
+		response["next_page_state"] = base64.RawURLEncoding.EncodeToString(next)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func GetUser(c *gin.Context) {
+	user, err := db.GetUserByEmail(c.Param("email"))
+	respondUser(c, user, err)
+}
+
+func GetUserByID(c *gin.Context) {
+	id, err := gocql.ParseUUID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+	user, err := db.GetUserByID(id)
+	respondUser(c, user, err)
+}
+
+// UpdateUser changes the name and/or role of a user
+func UpdateUser(c *gin.Context) {
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == nil && req.Role == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	user, err := db.GetUserByEmail(c.Param("email"))
+	if err != nil {
+		respondUser(c, nil, err)
+		return
+	}
+	email := user.Email
+
+	details := map[string]string{}
+	if req.Name != nil {
+		if *req.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+			return
+		}
+		details["name"] = user.Name + " -> " + *req.Name
+		user.Name = *req.Name
+	}
+	if req.Role != nil {
+		if *req.Role == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "role cannot be empty"})
+			return
+		}
+		if email == c.GetString("email") && *req.Role != user.Role {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Admins cannot change their own role"})
+			return
+		}
+		if !validRole(c, *req.Role) {
+			return
+		}
+		details["role"] = user.Role + " -> " + *req.Role
+	}
+	roleChanged := req.Role != nil && *req.Role != user.Role
+	if req.Role != nil {
+		user.Role = *req.Role
+	}
+
+	if err := db.Session.Query(`UPDATE users SET name = ?, role = ? WHERE email = ?`, user.Name, user.Role, email).Exec(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+	// Tokens carry the role, so the old ones must go; clients pick up the new
+	// role on their next refresh
+	if roleChanged {
+		if err := utils.RevokeUserTokens(user.ID.String()); err != nil {
+			log.Printf("❌ Failed to revoke tokens of %s after role change: %v", email, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Role changed but the old tokens could not be revoked"})
+			return
+		}
+	}
+	audit(c, "user.update", email, details)
+
+	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully", "user": user})
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+func DisableUser(c *gin.Context) {
+	setUserDisabled(c, c.Param("email"), true)
+}
+
+func EnableUser(c *gin.Context) {
+	setUserDisabled(c, c.Param("email"), false)
+}
+
+// ForceLogout revokes every session of a user
+func ForceLogout(c *gin.Context) {
+	user, err := db.GetUserByEmail(c.Param("email"))
+	if err != nil {
+		respondUser(c, nil, err)
+		return
+	}
+	email := user.Email
+
+	if err := signOutEverywhere(user.ID, email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out user"})
+		return
+	}
+	audit(c, "user.force_logout", email, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "User logged out from every device"})
+}
+
+// ListAuditLog returns one day of admin actions (day=YYYY-MM-DD, default today UTC)
+func ListAuditLog(c *gin.Context) {
+	day := c.DefaultQuery("day", time.Now().UTC().Format("2006-01-02"))
+	if _, err := time.Parse("2006-01-02", day); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "day must be YYYY-MM-DD"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+
+	entries, err := db.ListAudit(day, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read audit log"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"day": day, "entries": entries})
+}
+
+func setUserDisabled(c *gin.Context, email string, disabled bool) {
+	user, err := db.GetUserByEmail(email)
+	if err != nil {
+		respondUser(c, nil, err)
+		return
+	}
+	email = user.Email
+	if disabled && email == c.GetString("email") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Admins cannot disable their own account"})
+		return
+	}
+
+	var disabledAt interface{}
+	action := "user.enable"
+	if disabled {
+		disabledAt = time.Now()
+		action = "user.disable"
+	}
+	if err := db.Session.Query(`UPDATE users SET disabled = ?, disabled_at = ? WHERE email = ?`, disabled, disabledAt, email).Exec(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+	if disabled {
+		if err := signOutEverywhere(user.ID, email); err != nil {
+			log.Printf("❌ Failed to revoke sessions of disabled user %s: %v", email, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "User disabled but could not be signed out"})
+			return
+		}
+	}
+	audit(c, action, email, nil)
+
+	if disabled {
+		c.JSON(http.StatusOK, gin.H{"message": "User disabled successfully"})
+	} else {
+		c.JSON(http.StatusOK, gin.H{"message": "User enabled successfully"})
+	}
+}
+
+// signOutEverywhere revokes all sessions and access tokens of a user and clears isloggedin
+func signOutEverywhere(userID gocql.UUID, email string) error {
+	if err := db.RevokeUserSessions(userID); err != nil {
+		return err
+	}
+	if err := utils.RevokeUserTokens(userID.String()); err != nil {
+		return err
+	}
+	return db.Session.Query(`UPDATE users SET isloggedin = ? WHERE email = ?`, false, email).Exec()
+}
+
+func respondUser(c *gin.Context, user *models.UserSummary, err error) {
+	if err == gocql.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+// audit records an admin action performed by the caller
+func audit(c *gin.Context, action, target string, details map[string]string) {
+	db.RecordAudit(c.GetString("email"), action, target, c.ClientIP(), details)
+}
+
+func optionalBool(value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func optionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
 }
@@ -0,0 +1,100 @@
+package routes
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocql/gocql"
+
+	"Auth/db"
+	"Auth/internal/emailjob"
+	"Auth/internal/kafka"
+	"Auth/utils"
+)
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// VerifyEmail marks the account behind a verification link as verified
+func VerifyEmail(c *gin.Context) {
+	email, err := utils.ParseVerificationToken(c.Query("token"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Verification link is invalid or has expired"})
+		return
+	}
+
+	var isVerified bool
+	if err := db.Session.Query(`SELECT isverified FROM users WHERE email = ? LIMIT 1`, email).
+		Consistency(gocql.One).Scan(&isVerified); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Verification link is invalid or has expired"})
+		return
+	}
+	if isVerified {
+		c.JSON(http.StatusOK, gin.H{"message": "Email already verified"})
+		return
+	}
+
+	if err := db.Session.Query(`UPDATE users SET isverified = ?, verified_at = ? WHERE email = ?`,
+		true, time.Now(), email).Exec(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ResendVerification sends a fresh verification link to an unverified account.
+// The response never reveals whether the account exists.
+func ResendVerification(c *gin.Context) {
+	var req ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"message": "If the account exists and is unverified, a verification email has been sent"}
+
+	email, err := db.FindUserEmail(req.Email)
+	if err != nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	var isVerified bool
+	if err := db.Session.Query(`SELECT isverified FROM users WHERE email = ? LIMIT 1`, email).
+		Consistency(gocql.One).Scan(&isVerified); err != nil || isVerified {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	if err := queueVerificationEmail(emailjob.EmailJob{
+		To:      email,
+		Subject: "Confirm your email",
+		Type:    emailjob.TypeVerifyEmail,
+	}); err != nil {
+		log.Printf("❌ Failed to queue verification email for %s: %v", email, err)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// queueVerificationEmail signs a verification link into job and publishes it
+func queueVerificationEmail(job emailjob.EmailJob) error {
+	token, err := utils.GenerateVerificationToken(job.To)
+	if err != nil {
+		return err
+	}
+	job.Link = appBaseURL() + "/api/v0/verify?token=" + url.QueryEscape(token)
+	return kafka.PublishEmailJob(job)
+}
+
+// emailVerificationRequired reports whether unverified accounts are blocked from logging in
+func emailVerificationRequired() bool {
+	required, _ := strconv.ParseBool(os.Getenv("REQUIRE_EMAIL_VERIFICATION"))
+	return required
+}
@@ -0,0 +1,108 @@
+package test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"Auth/utils"
+	"shared/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useTestKeys points the key ring at an empty temporary directory, so a fresh
+// Ed25519 key is generated
+func useTestKeys(t *testing.T) string {
+	dir := t.TempDir()
+	t.Setenv("JWT_KEYS_DIR", dir)
+	t.Setenv("JWT_ACTIVE_KID", "")
+	require.NoError(t, utils.LoadSigningKeys())
+	utils.InitVerifier()
+	return dir
+}
+
+func writeRSAKey(t *testing.T, dir, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600))
+}
+
+func TestGeneratedKeyIsPublished(t *testing.T) {
+	useTestKeys(t)
+
+	keys := utils.JWKS()["keys"].([]map[string]string)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "OKP", keys[0]["kty"])
+	assert.Equal(t, "EdDSA", keys[0]["alg"])
+	assert.NotEmpty(t, keys[0]["x"])
+
+	token, err := utils.GenerateToken("user-1", "staff", "staff@example.com", "Staff", "session-1", false, nil)
+	require.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, keys[0]["kid"], parsed.Header["kid"])
+}
+
+func TestRotationKeepsOldTokensValid(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("JWT_KEYS_DIR", dir)
+	t.Setenv("JWT_ACTIVE_KID", "")
+	writeRSAKey(t, dir, "2026-01")
+	require.NoError(t, utils.LoadSigningKeys())
+	utils.InitVerifier()
+
+	oldToken, err := utils.GenerateToken("user-1", "staff", "staff@example.com", "Staff", "session-1", false, nil)
+	require.NoError(t, err)
+
+	// A newer key becomes active; the old one stays published
+	writeRSAKey(t, dir, "2026-07")
+	require.NoError(t, utils.LoadSigningKeys())
+	assert.Len(t, utils.JWKS()["keys"], 2)
+
+	newToken, err := utils.GenerateToken("user-1", "staff", "staff@example.com", "Staff", "session-1", false, nil)
+	require.NoError(t, err)
+	parsed, _, _ := jwt.NewParser().ParseUnverified(newToken, jwt.MapClaims{})
+	assert.Equal(t, "2026-07", parsed.Header["kid"])
+	assert.Equal(t, "RS256", parsed.Method.Alg())
+
+	_, err = utils.ParseToken(oldToken)
+	assert.NoError(t, err)
+
+	// Once the old key is removed its tokens stop verifying
+	require.NoError(t, os.Remove(filepath.Join(dir, "2026-01.pem")))
+	require.NoError(t, utils.LoadSigningKeys())
+	_, err = utils.ParseToken(oldToken)
+	assert.ErrorIs(t, err, auth.ErrUnknownKey)
+}
+
+func TestUnexpectedAlgorithmsAreRejected(t *testing.T) {
+	useTestKeys(t)
+	kid := utils.JWKS()["keys"].([]map[string]string)[0]["kid"]
+
+	claims := jwt.MapClaims{"user_id": "user-1", "role": "admin", "exp": time.Now().Add(time.Minute).Unix()}
+
+	hs := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	hs.Header["kid"] = kid
+	forged, err := hs.SignedString([]byte("supersecretkey"))
+	require.NoError(t, err)
+	_, err = utils.ParseToken(forged)
+	assert.Error(t, err)
+
+	none := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
+	none.Header["kid"] = kid
+	unsigned, err := none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	_, err = utils.ParseToken(unsigned)
+	assert.Error(t, err)
+}
@@ -0,0 +1,178 @@
+package test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"Auth/internal/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubIdP is a minimal OpenID provider: discovery, JWKS, authorize and token endpoints
+type stubIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]stubGrant
+}
+
+type stubGrant struct {
+	challenge string
+	nonce     string
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	idp := &stubIdP{key: key, codes: make(map[string]stubGrant)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "stub-key",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		idp.mu.Lock()
+		grant, ok := idp.codes[r.Form.Get("code")]
+		delete(idp.codes, r.Form.Get("code"))
+		idp.mu.Unlock()
+		if !ok || oidc.CodeChallengeS256(r.Form.Get("code_verifier")) != grant.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "stub-access",
+			"token_type":   "Bearer",
+			"id_token":     idp.idToken(t, r.Form.Get("client_id"), grant.nonce),
+		})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// authorize simulates the user approving the login at the provider
+func (idp *stubIdP) authorize(authURL string) string {
+	u, _ := url.Parse(authURL)
+	q := u.Query()
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.codes["code-123"] = stubGrant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	return "code-123"
+}
+
+func (idp *stubIdP) idToken(t *testing.T, audience, nonce string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"aud":            audience,
+		"sub":            "staff-42",
+		"email":          "staff@divyapacking.com",
+		"email_verified": true,
+		"nonce":          nonce,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(5 * time.Minute).Unix(),
+	})
+	token.Header["kid"] = "stub-key"
+	signed, err := token.SignedString(idp.key)
+	require.NoError(t, err)
+	return signed
+}
+
+func stubProvider(t *testing.T, idp *stubIdP) *oidc.Provider {
+	p, err := oidc.NewProvider(context.Background(), oidc.ProviderConfig{
+		Name:        "company",
+		Issuer:      idp.server.URL,
+		ClientID:    "auth-service",
+		RedirectURL: "http://localhost:8080/api/v0/oauth/callback",
+		Scopes:      []string{"openid", "email"},
+	}, idp.server.Client())
+	require.NoError(t, err)
+	return p
+}
+
+func TestOIDCCodeFlowAgainstStubIdP(t *testing.T) {
+	idp := newStubIdP(t)
+	provider := stubProvider(t, idp)
+
+	verifier, err := oidc.NewCodeVerifier()
+	require.NoError(t, err)
+	authURL := provider.AuthCodeURL("state-1", "nonce-1", oidc.CodeChallengeS256(verifier))
+	assert.Contains(t, authURL, "code_challenge_method=S256")
+
+	code := idp.authorize(authURL)
+	tokens, err := provider.Exchange(context.Background(), code, verifier)
+	require.NoError(t, err)
+
+	claims, err := provider.VerifyIDToken(context.Background(), tokens.IDToken, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, "staff-42", claims.Subject)
+	assert.Equal(t, "staff@divyapacking.com", claims.Email)
+}
+
+func TestOIDCRejectsWrongVerifierAndNonce(t *testing.T) {
+	idp := newStubIdP(t)
+	provider := stubProvider(t, idp)
+
+	verifier, _ := oidc.NewCodeVerifier()
+	code := idp.authorize(provider.AuthCodeURL("state-1", "nonce-1", oidc.CodeChallengeS256(verifier)))
+	_, err := provider.Exchange(context.Background(), code, "not-the-verifier")
+	assert.Error(t, err)
+
+	code = idp.authorize(provider.AuthCodeURL("state-2", "nonce-2", oidc.CodeChallengeS256(verifier)))
+	tokens, err := provider.Exchange(context.Background(), code, verifier)
+	require.NoError(t, err)
+	_, err = provider.VerifyIDToken(context.Background(), tokens.IDToken, "other-nonce")
+	assert.ErrorIs(t, err, oidc.ErrNonceMismatch)
+}
+
+func TestProviderAllowList(t *testing.T) {
+	registry := oidc.NewRegistry(map[string]oidc.ProviderConfig{}, nil)
+	_, err := registry.Provider(context.Background(), "github")
+	assert.ErrorIs(t, err, oidc.ErrUnknownProvider)
+
+	cfg := oidc.ProviderConfig{AllowedDomains: []string{"divyapacking.com"}}
+	assert.True(t, cfg.EmailAllowed("a@DivyaPacking.com"))
+	assert.False(t, cfg.EmailAllowed("a@example.com"))
+}
+
+func TestProviderEmailVerified(t *testing.T) {
+	yes, no := true, false
+	strict := oidc.ProviderConfig{}
+	assert.True(t, strict.EmailVerified(&yes))
+	assert.False(t, strict.EmailVerified(&no))
+	assert.False(t, strict.EmailVerified(nil), "a missing claim is not a verified email")
+
+	trusted := oidc.ProviderConfig{TrustEmail: true}
+	assert.True(t, trusted.EmailVerified(nil))
+	assert.False(t, trusted.EmailVerified(&no), "an explicit false wins over trust")
+}
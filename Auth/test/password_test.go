@@ -0,0 +1,139 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"Auth/db"
+	"Auth/models"
+	"Auth/routes"
+	"Auth/utils"
+	"shared/store/redistest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// useFakeRedis points utils.RDB at a new fake Redis for the test
+func useFakeRedis(t *testing.T) {
+	previous := utils.RDB
+	utils.RDB = redistest.NewServer(t).Client(t)
+	t.Cleanup(func() { utils.RDB = previous })
+}
+
+func TestResetTokenWorksOnce(t *testing.T) {
+	useFakeRedis(t)
+
+	token, err := utils.IssueResetToken("jane@example.com", time.Minute)
+	require.NoError(t, err)
+	email, err := utils.ConsumeResetToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", email)
+
+	_, err = utils.ConsumeResetToken(token)
+	assert.ErrorIs(t, err, utils.ErrInvalidResetToken, "a used link cannot reset again")
+	_, err = utils.ConsumeResetToken("made-up")
+	assert.ErrorIs(t, err, utils.ErrInvalidResetToken)
+}
+
+func TestOnlyLatestResetTokenIsValid(t *testing.T) {
+	useFakeRedis(t)
+
+	first, err := utils.IssueResetToken("jane@example.com", time.Minute)
+	require.NoError(t, err)
+	second, err := utils.IssueResetToken("jane@example.com", time.Minute)
+	require.NoError(t, err)
+	other, err := utils.IssueResetToken("joe@example.com", time.Minute)
+	require.NoError(t, err)
+
+	_, err = utils.ConsumeResetToken(first)
+	assert.ErrorIs(t, err, utils.ErrInvalidResetToken, "asking again replaces the link")
+	email, err := utils.ConsumeResetToken(second)
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", email)
+	email, err = utils.ConsumeResetToken(other)
+	require.NoError(t, err)
+	assert.Equal(t, "joe@example.com", email, "links of other accounts are untouched")
+}
+
+func TestResetTokenExpires(t *testing.T) {
+	useFakeRedis(t)
+
+	token, err := utils.IssueResetToken("jane@example.com", 50*time.Millisecond)
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	_, err = utils.ConsumeResetToken(token)
+	assert.ErrorIs(t, err, utils.ErrInvalidResetToken)
+}
+
+func TestPasswordChangesRevokeOtherSessions(t *testing.T) {
+	current, other, revoked := gocql.TimeUUID(), gocql.TimeUUID(), gocql.TimeUUID()
+	sessions := []models.Session{{ID: current}, {ID: other}, {ID: revoked, Revoked: true}}
+
+	// A change keeps the device it was made from
+	assert.Equal(t, []gocql.UUID{other}, db.SessionsToRevoke(sessions, current))
+	// A reset signs out every device
+	assert.Equal(t, []gocql.UUID{current, other}, db.SessionsToRevoke(sessions))
+}
+
+// fakePasswords is a PasswordStore for one user whose sessions cannot be revoked
+type fakePasswords struct {
+	id        gocql.UUID
+	hash      string
+	loggedOut bool
+}
+
+func (f *fakePasswords) UserID(string) (gocql.UUID, error)   { return f.id, nil }
+func (f *fakePasswords) PasswordHash(string) (string, error) { return f.hash, nil }
+func (f *fakePasswords) SetPassword(string, string) error    { return nil }
+func (f *fakePasswords) SetLoggedOut(string) error           { f.loggedOut = true; return nil }
+func (f *fakePasswords) RevokeUserSessions(gocql.UUID, ...gocql.UUID) error {
+	return errors.New("cassandra is down")
+}
+
+func usePasswords(t *testing.T, store routes.PasswordStore) {
+	previous := routes.Passwords
+	routes.Passwords = store
+	t.Cleanup(func() { routes.Passwords = previous })
+}
+
+func TestPasswordChangesFailWhenSessionsStaySignedIn(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useFakeRedis(t)
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	store := &fakePasswords{id: gocql.TimeUUID(), hash: string(hash)}
+	usePasswords(t, store)
+
+	router := gin.New()
+	router.POST("/password/reset", routes.ResetPassword)
+	router.POST("/password/change", func(c *gin.Context) {
+		c.Set("email", "jane@example.com")
+		c.Set("user_id", store.id.String())
+		c.Set("session_id", gocql.TimeUUID().String())
+		routes.ChangePassword(c)
+	})
+	post := func(path, body string) int {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	token, err := utils.IssueResetToken("jane@example.com", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError,
+		post("/password/reset", `{"token":"`+token+`","new_password":"new-password"}`))
+	assert.False(t, store.loggedOut, "the reset stops at the failed revocation")
+
+	assert.Equal(t, http.StatusInternalServerError,
+		post("/password/change", `{"old_password":"old-password","new_password":"new-password"}`))
+}
@@ -0,0 +1,101 @@
+package test
+
+import (
+	"testing"
+
+	"Auth/db"
+	"Auth/models"
+	"Auth/utils"
+	"shared/auth"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshTokenRoundTrip(t *testing.T) {
+	secret, err := utils.NewRefreshSecret()
+	assert.NoError(t, err)
+
+	token := utils.FormatRefreshToken("user-1", "session-1", secret)
+	userID, sessionID, got, err := utils.ParseRefreshToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+	assert.Equal(t, "session-1", sessionID)
+	assert.Equal(t, secret, got)
+}
+
+func TestParseRefreshTokenRejectsMalformed(t *testing.T) {
+	for _, token := range []string{"", "abc", "a.b", "a..c", "a.b.c.d"} {
+		_, _, _, err := utils.ParseRefreshToken(token)
+		assert.ErrorIs(t, err, utils.ErrInvalidRefreshToken, token)
+	}
+}
+
+func TestAccessTokenCarriesSession(t *testing.T) {
+	useTestKeys(t)
+
+	token, err := utils.GenerateToken("user-1", "staff", "staff@example.com", "Staff", "session-1", false, []string{"chat:read"})
+	assert.NoError(t, err)
+
+	claims, err := utils.ParseToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "session-1", claims.SessionID)
+	assert.Equal(t, "staff", claims.Role)
+	assert.Equal(t, []string{"chat:read"}, claims.Permissions)
+}
+
+func TestAccessTokenRegisteredClaims(t *testing.T) {
+	useTestKeys(t)
+	t.Setenv("JWT_TOKEN_AUDIENCES", "")
+
+	first, err := utils.GenerateToken("user-1", "staff", "staff@example.com", "Staff", "session-1", false, nil)
+	assert.NoError(t, err)
+	second, _ := utils.GenerateToken("user-1", "staff", "staff@example.com", "Staff", "session-1", false, nil)
+
+	a, err := utils.ParseToken(first)
+	assert.NoError(t, err)
+	b, _ := utils.ParseToken(second)
+	assert.Equal(t, "auth", a.Issuer)
+	assert.Equal(t, "user-1", a.Subject)
+	assert.ElementsMatch(t, []string{"auth", "camera", "feedback"}, a.Audience)
+	assert.NotNil(t, a.IssuedAt)
+	assert.NotEqual(t, a.ID, b.ID, "every token gets its own jti")
+
+	// A token minted for other services only is rejected here
+	t.Setenv("JWT_TOKEN_AUDIENCES", "camera")
+	cameraOnly, _ := utils.GenerateToken("user-1", "staff", "staff@example.com", "Staff", "session-1", false, nil)
+	_, err = utils.ParseToken(cameraOnly)
+	assert.Equal(t, auth.ReasonInvalidAudience, auth.ReasonOf(err))
+}
+
+func TestHashTokenIsStable(t *testing.T) {
+	assert.Equal(t, utils.HashToken("abc"), utils.HashToken("abc"))
+	assert.NotEqual(t, utils.HashToken("abc"), utils.HashToken("abd"))
+}
+
+func TestVerificationTokenIsSinglePurpose(t *testing.T) {
+	useTestKeys(t)
+
+	token, err := utils.GenerateVerificationToken("staff@example.com")
+	assert.NoError(t, err)
+
+	email, err := utils.ParseVerificationToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "staff@example.com", email)
+
+	_, err = utils.ParseToken(token)
+	assert.ErrorIs(t, err, utils.ErrWrongTokenPurpose)
+
+	access, _ := utils.GenerateToken("user-1", "staff", "staff@example.com", "Staff", "session-1", false, []string{"chat:read"})
+	_, err = utils.ParseVerificationToken(access)
+	assert.Error(t, err)
+}
+
+func TestOnlyTheRotatedRefreshTokenCountsAsReuse(t *testing.T) {
+	session := &models.Session{RefreshHash: utils.HashToken("current")}
+	assert.False(t, db.IsReusedRefresh(session, utils.HashToken("guess")), "never rotated")
+	assert.False(t, db.IsReusedRefresh(session, ""))
+
+	session.PreviousRefreshHash = utils.HashToken("previous")
+	assert.True(t, db.IsReusedRefresh(session, utils.HashToken("previous")))
+	assert.False(t, db.IsReusedRefresh(session, utils.HashToken("guess")), "a wrong secret is not a replay")
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"Auth/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 6238 appendix B secret "12345678901234567890" in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPMatchesRFCVectors(t *testing.T) {
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range cases {
+		code, err := utils.TOTPCode(rfcSecret, utils.TOTPStep(time.Unix(unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, want, code, unix)
+	}
+}
+
+func TestVerifyTOTPAllowsOneStepOfSkew(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	previous, _ := utils.TOTPCode(rfcSecret, utils.TOTPStep(now)-1)
+	stale, _ := utils.TOTPCode(rfcSecret, utils.TOTPStep(now)-3)
+
+	step, ok := utils.VerifyTOTP(rfcSecret, previous, now)
+	assert.True(t, ok)
+	assert.Equal(t, utils.TOTPStep(now)-1, step)
+
+	_, ok = utils.VerifyTOTP(rfcSecret, stale, now)
+	assert.False(t, ok)
+	_, ok = utils.VerifyTOTP(rfcSecret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestOTPAuthURIAndRecoveryCodes(t *testing.T) {
+	secret, err := utils.NewTOTPSecret()
+	assert.NoError(t, err)
+	uri := utils.OTPAuthURI("admin@divyapacking.com", secret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/"))
+	assert.Contains(t, uri, "secret="+secret)
+
+	codes, err := utils.GenerateRecoveryCodes(10)
+	assert.NoError(t, err)
+	assert.Len(t, codes, 10)
+	assert.Equal(t, codes[0], utils.NormalizeRecoveryCode(" "+strings.ToUpper(codes[0])+" "))
+}
+
+func TestMFARequiredForAdminByDefault(t *testing.T) {
+	assert.True(t, utils.MFARequiredForRole("admin"))
+	assert.False(t, utils.MFARequiredForRole("staff"))
+
+	t.Setenv("MFA_REQUIRED_ROLES", "admin,supervisor")
+	assert.True(t, utils.MFARequiredForRole("supervisor"))
+}
+
+func TestTokensWithoutMFACarryNoPermissions(t *testing.T) {
+	useTestKeys(t)
+	perms := []string{"*"}
+	assert.Empty(t, utils.TokenPermissions("admin", perms, false))
+	assert.Equal(t, perms, utils.TokenPermissions("admin", perms, true))
+	assert.Equal(t, []string{"chat:read"}, utils.TokenPermissions("staff", []string{"chat:read"}, false))
+
+	token, err := utils.GenerateToken("u1", "admin", "a@example.com", "Admin", "s1", false, utils.TokenPermissions("admin", perms, false))
+	assert.NoError(t, err)
+	claims, err := utils.ParseToken(token)
+	assert.NoError(t, err)
+	assert.False(t, claims.Can("camera:view:dock"))
+}
@@ -7,6 +7,8 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"Auth/utils"
+
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
@@ -56,3 +58,7 @@ func TestCreateUserEndpoint(t *testing.T) {
 	assert.Equal(t, http.StatusCreated, resp.Code)
 }
 
+func TestNormalizeEmail(t *testing.T) {
+	assert.Equal(t, "jane.doe@example.com", utils.NormalizeEmail("  Jane.Doe@Example.COM "))
+	assert.Equal(t, utils.NormalizeEmail("a@b.io"), utils.NormalizeEmail("A@B.IO"), "logins match whatever the case")
+}
@@ -114,22 +114,7 @@ import (
 // 	return SendEmail(to, subject, body)
 // }
 //for development
-func SendWelcomeEmail(to, name string) error {
-	// Load SMTP credentials from environment variables
-	smtpHost := os.Getenv("SMTP_HOST")
-	smtpPortStr := os.Getenv("SMTP_PORT")
-	smtpUser := os.Getenv("SMTP_USER")
-	smtpPass := os.Getenv("SMTP_PASS")
-
-	if smtpHost == "" || smtpPortStr == "" || smtpUser == "" || smtpPass == "" {
-		return fmt.Errorf("missing SMTP configuration environment variables")
-	}
-
-	port, err := strconv.Atoi(smtpPortStr)
-	if err != nil {
-		return fmt.Errorf("invalid SMTP_PORT: %v", err)
-	}
-
+func SendWelcomeEmail(to, name, verifyLink string) error {
 	// Create the HTML message
 	body := fmt.Sprintf(`
 		<!DOCTYPE html>
@@ -176,8 +161,8 @@ func SendWelcomeEmail(to, name string) error {
 			<div class="container">
 				<h1>Welcome, %s 👋</h1>
 				<p>We’re thrilled to have you at <strong>Divya Packing</strong>!</p>
-				<p>Your account has been successfully created. You can now log in and start exploring our secure authentication platform.</p>
-				<a href="https://yourdomain.com/login" class="button">Get Started</a>
+				<p>Your account has been successfully created. Please confirm your email address to start exploring our secure authentication platform.</p>
+				<a href="%s" class="button">Verify Email</a>
 				<div class="footer">
 					<p>If you didn’t create this account, please ignore this email.</p>
 					<p>© 2025 Divya Packing. All rights reserved.</p>
@@ -185,13 +170,92 @@ func SendWelcomeEmail(to, name string) error {
 			</div>
 		</body>
 		</html>
-	`, name)
+	`, name, verifyLink)
+
+	return sendSMTP(to, "Welcome to Divya Packing 🎉", body)
+}
+
+// SendVerificationEmail re-sends the email verification link
+func SendVerificationEmail(to, link string) error {
+	body := emailLayout("Confirm your email", fmt.Sprintf(`
+				<p>Please confirm that <strong>%s</strong> is your email address.</p>
+				<a href="%s" class="button">Verify Email</a>
+				<p>If you didn’t create this account, please ignore this email.</p>`, to, link))
+
+	return sendSMTP(to, "Confirm your Divya Packing email", body)
+}
+
+// SendAccountLockedEmail warns the owner that repeated failed logins locked the account
+func SendAccountLockedEmail(to, detail string) error {
+	body := emailLayout("Your account was locked", fmt.Sprintf(`
+				<p>%s</p>
+				<p>If this was you, wait for the lock to expire or reset your password.</p>
+				<p>If it wasn’t you, someone may be trying to guess your password — please contact your administrator.</p>`, detail))
+
+	return sendSMTP(to, "Security alert: your Divya Packing account was locked", body)
+}
+
+// SendPasswordResetEmail sends the single-use password reset link
+func SendPasswordResetEmail(to, link string) error {
+	body := emailLayout("Reset your password", fmt.Sprintf(`
+				<p>We received a request to reset the password for <strong>%s</strong>.</p>
+				<p>This link can be used once and expires shortly.</p>
+				<a href="%s" class="button">Reset Password</a>
+				<p>If you didn’t request a reset, you can safely ignore this email.</p>`, to, link))
+
+	return sendSMTP(to, "Reset your Divya Packing password", body)
+}
+
+// emailLayout wraps content in the same look as the welcome email
+func emailLayout(title, content string) string {
+	return fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html>
+		<head>
+			<meta charset="UTF-8">
+			<title>%[1]s</title>
+			<style>
+				body { font-family: Arial, sans-serif; background-color: #f7f9fc; color: #333; padding: 20px; }
+				.container { max-width: 600px; margin: 0 auto; background: white; border-radius: 12px; padding: 30px; box-shadow: 0 4px 12px rgba(0,0,0,0.08); }
+				h1 { color: #0061ff; font-size: 24px; }
+				.button { display: inline-block; padding: 10px 20px; background-color: #0061ff; color: white; border-radius: 6px; text-decoration: none; margin-top: 20px; }
+				.footer { margin-top: 30px; font-size: 12px; color: #888; }
+			</style>
+		</head>
+		<body>
+			<div class="container">
+				<h1>%[1]s</h1>%[2]s
+				<div class="footer">
+					<p>© 2025 Divya Packing. All rights reserved.</p>
+				</div>
+			</div>
+		</body>
+		</html>
+	`, title, content)
+}
+
+// sendSMTP delivers an HTML email through the configured SMTP server, retrying on failure
+func sendSMTP(to, subject, body string) error {
+	// Load SMTP credentials from environment variables
+	smtpHost := os.Getenv("SMTP_HOST")
+	smtpPortStr := os.Getenv("SMTP_PORT")
+	smtpUser := os.Getenv("SMTP_USER")
+	smtpPass := os.Getenv("SMTP_PASS")
+
+	if smtpHost == "" || smtpPortStr == "" || smtpUser == "" || smtpPass == "" {
+		return fmt.Errorf("missing SMTP configuration environment variables")
+	}
+
+	port, err := strconv.Atoi(smtpPortStr)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP_PORT: %v", err)
+	}
 
 	// Prepare message
 	m := gomail.NewMessage()
 	m.SetHeader("From", fmt.Sprintf("Divya Packing <%s>", smtpUser))
 	m.SetHeader("To", to)
-	m.SetHeader("Subject", "Welcome to Divya Packing 🎉")
+	m.SetHeader("Subject", subject)
 	m.SetBody("text/html", body)
 
 	// Set up dialer
@@ -202,7 +266,7 @@ func SendWelcomeEmail(to, name string) error {
 	for attempt := 1; attempt <= maxRetries; attempt++ {
 		err := d.DialAndSend(m)
 		if err == nil {
-			log.Printf("📧 Welcome email sent successfully to %s", to)
+			log.Printf("📧 Email %q sent successfully to %s", subject, to)
 			return nil
 		}
 
@@ -212,5 +276,5 @@ func SendWelcomeEmail(to, name string) error {
 		}
 	}
 
-	return fmt.Errorf("failed to send welcome email after %d attempts", maxRetries)
-}
\ No newline at end of file
+	return fmt.Errorf("failed to send email after %d attempts", maxRetries)
+}
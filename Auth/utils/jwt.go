@@ -1,36 +1,185 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"shared/auth"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateToken(userID string, role string, email string, name string) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
+var (
+	// ErrInvalidRefreshToken is returned when a refresh token is not in the expected format
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrWrongTokenPurpose is returned when a single-purpose token is used for something else
+	ErrWrongTokenPurpose = auth.ErrWrongTokenPurpose
+)
+
+// Purposes of the short-lived tokens we sign besides access tokens
+const (
+	PurposeEmailVerify  = "email_verify"
+	PurposeMFAChallenge = "mfa_challenge"
+)
+
+func init() {
+	// Times in our tokens are to the millisecond, so a token refreshed in the
+	// same second its user's tokens were revoked is told apart from the revoked ones
+	jwt.TimePrecision = time.Millisecond
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": userID,
-		"role":    role,
-		"email":   email,
-		"name":    name,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
+// AccessTokenTTL returns the lifetime of access tokens (JWT_EXPIRY_MINUTES, default 15)
+func AccessTokenTTL() time.Duration {
+	return envDuration("JWT_EXPIRY_MINUTES", 15, time.Minute)
+}
+
+// RefreshTokenTTL returns the absolute lifetime of a session (REFRESH_TOKEN_EXPIRY_HOURS, default 720)
+func RefreshTokenTTL() time.Duration {
+	return envDuration("REFRESH_TOKEN_EXPIRY_HOURS", 720, time.Hour)
+}
+
+// GenerateToken signs an access token. perms are the permissions of role at
+// signing time; services without access to the role table authorize from them.
+func GenerateToken(userID string, role string, email string, name string, sessionID string, mfa bool, perms []string) (string, error) {
+	jti, err := NewRefreshSecret()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	return signToken(&auth.Claims{
+		UserID:      userID,
+		Role:        role,
+		Email:       email,
+		Name:        name,
+		SessionID:   sessionID,
+		MFA:         mfa,
+		Permissions: perms,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer(),
+			Subject:   userID,
+			Audience:  tokenAudiences(),
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL())),
+		},
 	})
+}
+
+// tokenIssuer is the iss of our tokens (JWT_ISSUER, default "auth")
+func tokenIssuer() string {
+	if iss := os.Getenv("JWT_ISSUER"); iss != "" {
+		return iss
+	}
+	return auth.DefaultIssuer
+}
+
+// tokenAudiences lists the services an access token is valid for
+// (JWT_TOKEN_AUDIENCES, default "auth,camera,feedback")
+func tokenAudiences() jwt.ClaimStrings {
+	var audiences jwt.ClaimStrings
+	for _, aud := range strings.Split(os.Getenv("JWT_TOKEN_AUDIENCES"), ",") {
+		if aud = strings.TrimSpace(aud); aud != "" {
+			audiences = append(audiences, aud)
+		}
+	}
+	if len(audiences) == 0 {
+		audiences = jwt.ClaimStrings{"auth", "camera", "feedback"}
+	}
+	return audiences
+}
+
+// ParseToken verifies an access token. Errors are *auth.TokenError.
+func ParseToken(tokenStr string) (*auth.Claims, error) {
+	return Verifier.Verify(tokenStr)
+}
+
+// EmailVerifyTTL returns how long verification links stay valid (EMAIL_VERIFY_TTL_HOURS, default 48)
+func EmailVerifyTTL() time.Duration {
+	return envDuration("EMAIL_VERIFY_TTL_HOURS", 48, time.Hour)
+}
 
-	return token.SignedString([]byte(secret))
+// GenerateVerificationToken signs a token proving ownership of email
+func GenerateVerificationToken(email string) (string, error) {
+	return GeneratePurposeToken(PurposeEmailVerify, email, EmailVerifyTTL())
 }
 
-func ParseToken(tokenStr string) (jwt.MapClaims, error) {
-	secret := os.Getenv("JWT_SECRET")
+// ParseVerificationToken returns the email a verification token was issued for
+func ParseVerificationToken(tokenStr string) (string, error) {
+	claims, err := ParsePurposeToken(tokenStr, PurposeEmailVerify)
+	if err != nil {
+		return "", err
+	}
+	return claims["sub"].(string), nil
+}
 
-	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
+// GeneratePurposeToken signs a short-lived token that can only be used for purpose
+func GeneratePurposeToken(purpose, subject string, ttl time.Duration) (string, error) {
+	return signToken(jwt.MapClaims{
+		"sub":     subject,
+		"purpose": purpose,
+		"exp":     time.Now().Add(ttl).Unix(),
 	})
+}
 
+// ParsePurposeToken validates a token produced by GeneratePurposeToken for purpose
+func ParsePurposeToken(tokenStr, purpose string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenStr, auth.KeyFunc(localKeys{}), jwt.WithValidMethods(auth.Algorithms), jwt.WithExpirationRequired())
 	if err != nil || !token.Valid {
 		return nil, err
 	}
 
-	return token.Claims.(jwt.MapClaims), nil
+	claims := token.Claims.(jwt.MapClaims)
+	if p, _ := claims["purpose"].(string); p != purpose {
+		return nil, ErrWrongTokenPurpose
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, ErrWrongTokenPurpose
+	}
+	return claims, nil
+}
+
+// NewRefreshSecret returns a random, URL-safe secret for a refresh token
+func NewRefreshSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken returns the hex SHA-256 of a token so only hashes are persisted
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// FormatRefreshToken builds the opaque refresh token handed to clients.
+// The user and session ids let the server locate the session row directly.
+func FormatRefreshToken(userID, sessionID, secret string) string {
+	return userID + "." + sessionID + "." + secret
+}
+
+// ParseRefreshToken splits a refresh token into user id, session id and secret
+func ParseRefreshToken(token string) (userID, sessionID, secret string, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", ErrInvalidRefreshToken
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+func envDuration(key string, fallback int, unit time.Duration) time.Duration {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return time.Duration(v) * unit
+	}
+	return time.Duration(fallback) * unit
 }
@@ -0,0 +1,244 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"shared/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Tokens are signed with private keys read from JWT_KEYS_DIR (default "keys").
+// Every "<kid>.pem" file there holds one PKCS#8 RSA or Ed25519 key; the file name
+// is the key id. All keys are published on the JWKS endpoint, and tokens are
+// signed with JWT_ACTIVE_KID, or the last kid in sort order when unset, so naming
+// keys by date makes the newest one active.
+//
+// To rotate, drop a new key in the directory and keep the old file until every
+// token it signed has expired (the longest lived are email verification links).
+
+// Verifier checks our own access tokens against the loaded keys
+var Verifier *auth.Verifier
+
+// InitVerifier sets up Verifier. Call it after the environment is loaded and
+// Redis is connected; without Redis revoked tokens are not rejected.
+func InitVerifier() {
+	opts := auth.VerifierOptionsFromEnv("auth")
+	if RDB != nil {
+		Revocations = auth.NewRevocations(RDB)
+		opts.Revocations = Revocations
+	}
+	Verifier = auth.NewVerifier(localKeys{}, opts)
+}
+
+type signingKey struct {
+	kid    string
+	method jwt.SigningMethod
+	signer crypto.Signer
+}
+
+var keyRing struct {
+	sync.RWMutex
+	active *signingKey
+	byKid  map[string]*signingKey
+}
+
+// LoadSigningKeys (re)reads the key directory. When it holds no key a new
+// Ed25519 key is generated there so a fresh checkout can run.
+func LoadSigningKeys() error {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		dir = "keys"
+	}
+
+	loaded, err := readSigningKeys(dir)
+	if err != nil {
+		return err
+	}
+	if len(loaded) == 0 {
+		key, err := generateSigningKey(dir)
+		if err != nil {
+			return err
+		}
+		log.Printf("⚠️ No signing keys in %s — generated %s", dir, key.kid)
+		loaded[key.kid] = key
+	}
+
+	kids := make([]string, 0, len(loaded))
+	for kid := range loaded {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	activeKid := os.Getenv("JWT_ACTIVE_KID")
+	if activeKid == "" {
+		activeKid = kids[len(kids)-1]
+	}
+	active, ok := loaded[activeKid]
+	if !ok {
+		return fmt.Errorf("active signing key %q not found in %s", activeKid, dir)
+	}
+
+	keyRing.Lock()
+	keyRing.byKid = loaded
+	keyRing.active = active
+	keyRing.Unlock()
+	return nil
+}
+
+// StartKeyReloader re-reads the key directory every interval so rotated keys
+// are picked up without a restart
+func StartKeyReloader(interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			if err := LoadSigningKeys(); err != nil {
+				log.Printf("❌ Failed to reload signing keys: %v", err)
+			}
+		}
+	}()
+}
+
+// JWKS returns the public keys as a JSON Web Key Set
+func JWKS() map[string]interface{} {
+	keyRing.RLock()
+	defer keyRing.RUnlock()
+
+	kids := make([]string, 0, len(keyRing.byKid))
+	for kid := range keyRing.byKid {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	keys := make([]map[string]string, 0, len(kids))
+	for _, kid := range kids {
+		key := keyRing.byKid[kid]
+		jwk := map[string]string{"kid": kid, "use": "sig", "alg": key.method.Alg()}
+		switch pub := key.signer.Public().(type) {
+		case *rsa.PublicKey:
+			jwk["kty"] = "RSA"
+			jwk["n"] = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk["kty"] = "OKP"
+			jwk["crv"] = "Ed25519"
+			jwk["x"] = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		keys = append(keys, jwk)
+	}
+	return map[string]interface{}{"keys": keys}
+}
+
+// signToken signs claims with the active key and stamps its kid in the header
+func signToken(claims jwt.Claims) (string, error) {
+	keyRing.RLock()
+	key := keyRing.active
+	keyRing.RUnlock()
+	if key == nil {
+		return "", errors.New("signing keys not loaded")
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.signer)
+}
+
+// localKeys is the auth.KeySource of the key ring
+type localKeys struct{}
+
+func (localKeys) Key(kid string) (string, interface{}, error) {
+	keyRing.RLock()
+	key, ok := keyRing.byKid[kid]
+	keyRing.RUnlock()
+	if !ok {
+		return "", nil, auth.ErrUnknownKey
+	}
+	return key.method.Alg(), key.signer.Public(), nil
+}
+
+func readSigningKeys(dir string) (map[string]*signingKey, error) {
+	loaded := make(map[string]*signingKey)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return loaded, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".pem") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		key, err := parseSigningKey(strings.TrimSuffix(name, ".pem"), data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		loaded[key.kid] = key
+	}
+	return loaded, nil
+}
+
+func parseSigningKey(kid string, data []byte) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		return &signingKey{kid: kid, method: jwt.SigningMethodRS256, signer: key}, nil
+	case ed25519.PrivateKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, signer: key}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+}
+
+func generateSigningKey(dir string) (*signingKey, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	kid := time.Now().UTC().Format("20060102-150405")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+		return nil, err
+	}
+	return &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, signer: private}, nil
+}
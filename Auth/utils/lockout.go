@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// backoffAfter is the number of failures tolerated before delays kick in
+	backoffAfter = 2
+	maxBackoff   = 30 * time.Second
+	maxLockout   = 24 * time.Hour
+	// lockoutMemory is how long previous lockouts make the next one longer
+	lockoutMemory = 24 * time.Hour
+)
+
+// LoginBlock describes why an account can't try a password right now
+type LoginBlock struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+// LoginBlocked reports whether email is locked out or still in a backoff delay
+func LoginBlocked(email string) (LoginBlock, error) {
+	email = NormalizeEmail(email)
+
+	ttl, err := RDB.PTTL(Ctx, "login:lock:"+email).Result()
+	if err != nil {
+		return LoginBlock{}, err
+	}
+	if ttl > 0 {
+		return LoginBlock{RetryAfter: ttl, Locked: true}, nil
+	}
+
+	ttl, err = RDB.PTTL(Ctx, "login:backoff:"+email).Result()
+	if err != nil {
+		return LoginBlock{}, err
+	}
+	if ttl > 0 {
+		return LoginBlock{RetryAfter: ttl}, nil
+	}
+	return LoginBlock{}, nil
+}
+
+// RecordLoginFailure counts a failed attempt. Each failure past the first few
+// doubles the wait before the next attempt; reaching LOGIN_MAX_ATTEMPTS locks the
+// account, and every lockout within a day lasts twice as long as the previous one.
+// It returns the lockout duration when this failure triggered one.
+func RecordLoginFailure(email string) (time.Duration, error) {
+	email = NormalizeEmail(email)
+	failKey := "login:fail:" + email
+
+	count, err := RDB.Incr(Ctx, failKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		// The window starts with the first failure
+		RDB.Expire(Ctx, failKey, envDuration("LOGIN_FAIL_WINDOW_MINUTES", 15, time.Minute))
+	}
+
+	if count >= int64(envInt("LOGIN_MAX_ATTEMPTS", 5)) {
+		lockouts, err := RDB.Incr(Ctx, "login:lockcount:"+email).Result()
+		if err != nil {
+			return 0, err
+		}
+		RDB.Expire(Ctx, "login:lockcount:"+email, lockoutMemory)
+
+		lockFor := envDuration("LOGIN_LOCKOUT_MINUTES", 15, time.Minute) << (lockouts - 1)
+		if lockFor <= 0 || lockFor > maxLockout {
+			lockFor = maxLockout
+		}
+		pipe := RDB.TxPipeline()
+		pipe.Set(Ctx, "login:lock:"+email, lockouts, lockFor)
+		pipe.Del(Ctx, failKey, "login:backoff:"+email)
+		if _, err := pipe.Exec(Ctx); err != nil {
+			return 0, err
+		}
+		return lockFor, nil
+	}
+
+	if count > backoffAfter {
+		backoff := time.Second << (count - backoffAfter - 1)
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		if err := RDB.Set(Ctx, "login:backoff:"+email, 1, backoff).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return 0, nil
+}
+
+// ResetLoginFailures clears the failure counter after a successful login
+func ResetLoginFailures(email string) error {
+	email = NormalizeEmail(email)
+	return RDB.Del(Ctx, "login:fail:"+email, "login:backoff:"+email).Err()
+}
+
+// UnlockAccount lifts a lockout and forgets previous failures and lockouts
+func UnlockAccount(email string) (bool, error) {
+	email = NormalizeEmail(email)
+	locked, err := RDB.Exists(Ctx, "login:lock:"+email).Result()
+	if err != nil {
+		return false, err
+	}
+	if err := RDB.Del(Ctx, "login:lock:"+email, "login:lockcount:"+email, "login:fail:"+email, "login:backoff:"+email).Err(); err != nil {
+		return false, err
+	}
+	return locked > 0, nil
+}
+
+// NormalizeEmail returns email in the form accounts are stored and counted
+// under: trimmed and lower case
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func envInt(key string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrInvalidResetToken is returned for reset tokens that were used, replaced
+// by a newer one or expired
+var ErrInvalidResetToken = errors.New("reset link is invalid or has expired")
+
+// IssueResetToken returns a new password reset token for email, valid for
+// ttl. Only the latest token of an account stays valid.
+func IssueResetToken(email string, ttl time.Duration) (string, error) {
+	token, err := NewRefreshSecret()
+	if err != nil {
+		return "", err
+	}
+	hash := HashToken(token)
+
+	if previous, err := RDB.Get(Ctx, "pwreset:user:"+email).Result(); err == nil {
+		RDB.Del(Ctx, "pwreset:"+previous)
+	}
+	pipe := RDB.TxPipeline()
+	pipe.Set(Ctx, "pwreset:"+hash, email, ttl)
+	pipe.Set(Ctx, "pwreset:user:"+email, hash, ttl)
+	if _, err := pipe.Exec(Ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ConsumeResetToken returns the email a reset token was issued for. A token
+// works once.
+func ConsumeResetToken(token string) (string, error) {
+	email, err := RDB.GetDel(Ctx, "pwreset:"+HashToken(token)).Result()
+	if err == redis.Nil {
+		return "", ErrInvalidResetToken
+	}
+	if err != nil {
+		return "", err
+	}
+	RDB.Del(Ctx, "pwreset:user:"+email)
+	return email, nil
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// permissionCacheTTL bounds how stale a cached role can be on other instances
+const permissionCacheTTL = 5 * time.Minute
+
+// CachedRolePermissions returns the cached permissions of role, if any
+func CachedRolePermissions(role string) ([]string, bool) {
+	raw, err := RDB.Get(Ctx, "rbac:role:"+role).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	var perms []string
+	if err := json.Unmarshal(raw, &perms); err != nil {
+		return nil, false
+	}
+	return perms, true
+}
+
+// CacheRolePermissions stores the permissions of role in Redis
+func CacheRolePermissions(role string, perms []string) error {
+	data, err := json.Marshal(perms)
+	if err != nil {
+		return err
+	}
+	return RDB.Set(Ctx, "rbac:role:"+role, data, permissionCacheTTL).Err()
+}
+
+// InvalidateRolePermissions drops the cached permissions of role
+func InvalidateRolePermissions(role string) error {
+	return RDB.Del(Ctx, "rbac:role:"+role).Err()
+}
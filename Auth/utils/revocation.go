@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"time"
+
+	"shared/auth"
+)
+
+// Revocations is the token revocation list shared with the other services. It
+// is set by InitVerifier once Redis is connected.
+var Revocations *auth.Revocations
+
+// revocationTTL outlives every access token signed so far
+func revocationTTL() time.Duration {
+	return AccessTokenTTL() + auth.VerifierOptionsFromEnv("auth").Leeway
+}
+
+// RevokeToken revokes a single access token until it expires
+func RevokeToken(claims *auth.Claims) error {
+	return Revocations.RevokeToken(Ctx, claims)
+}
+
+// RevokeJTI revokes the access token with id jti when its expiry is unknown
+func RevokeJTI(jti string) error {
+	return Revocations.RevokeJTI(Ctx, jti, revocationTTL())
+}
+
+// RevokeSessionTokens revokes every access token of a session
+func RevokeSessionTokens(sessionID string) error {
+	return Revocations.RevokeSession(Ctx, sessionID, revocationTTL())
+}
+
+// RevokeUserTokens revokes every access token issued to a user so far. Sessions
+// stay open, so clients get new tokens (with fresh claims) on their next refresh.
+func RevokeUserTokens(userID string) error {
+	return Revocations.RevokeUser(Ctx, userID, revocationTTL())
+}
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"shared/store"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Channel is a chat room. Line and shift channels are shared by everyone who
+// joins them, direct channels have exactly two members.
+type Channel struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"` // global, line, shift or direct
+	Mode string `json:"mode"` // ModeOpen when empty
+	// SlowSeconds is the time between two messages of a user in ModeSlow
+	SlowSeconds int       `json:"slow_seconds,omitempty"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func CreateChannelTables() {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS channels (
+			id TEXT PRIMARY KEY,
+			kind TEXT,
+			created_by TEXT,
+			created_at TIMESTAMP,
+			mode TEXT,
+			slow_seconds INT
+		);`,
+		// Members of a channel, and the same rows by user to load a user's channels on connect
+		`CREATE TABLE IF NOT EXISTS channel_members (
+			channel_id TEXT,
+			user_id TEXT,
+			joined_at TIMESTAMP,
+			PRIMARY KEY (channel_id, user_id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS user_channels (
+			user_id TEXT,
+			channel_id TEXT,
+			joined_at TIMESTAMP,
+			PRIMARY KEY (user_id, channel_id)
+		);`,
+	}
+	for _, query := range queries {
+		if err := Session.Query(query).Exec(); err != nil {
+			log.Printf("❌ Error creating channel tables: %v", err)
+			return
+		}
+	}
+	// Tables created before channels had moderation modes
+	if _, err := store.EnsureColumns(Session, Keyspace, "channels", [][2]string{
+		{"mode", "TEXT"},
+		{"slow_seconds", "INT"},
+	}); err != nil {
+		log.Printf("❌ Error migrating channels table: %v", err)
+		return
+	}
+	fmt.Println("✅ Channel tables are ready")
+}
+
+// GetChannel returns the channel with id, or nil when there is none
+func GetChannel(id string) (*Channel, error) {
+	var ch Channel
+	err := Session.Query(`SELECT id, kind, created_by, created_at, mode, slow_seconds FROM channels WHERE id = ?`, id).
+		Scan(&ch.ID, &ch.Kind, &ch.CreatedBy, &ch.CreatedAt, &ch.Mode, &ch.SlowSeconds)
+	if err == gocql.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ch, nil
+}
+
+// CreateChannel stores ch unless a channel with its id already exists
+func CreateChannel(ch Channel) error {
+	return Session.Query(`INSERT INTO channels (id, kind, created_by, created_at) VALUES (?, ?, ?, ?) IF NOT EXISTS`,
+		ch.ID, ch.Kind, ch.CreatedBy, ch.CreatedAt).Exec()
+}
+
+// AddMember makes userID a member of channelID
+func AddMember(channelID, userID string) error {
+	now := time.Now()
+	batch := Session.NewBatch(gocql.LoggedBatch)
+	batch.Query(`INSERT INTO channel_members (channel_id, user_id, joined_at) VALUES (?, ?, ?)`, channelID, userID, now)
+	batch.Query(`INSERT INTO user_channels (user_id, channel_id, joined_at) VALUES (?, ?, ?)`, userID, channelID, now)
+	return Session.ExecuteBatch(batch)
+}
+
+// RemoveMember takes userID out of channelID
+func RemoveMember(channelID, userID string) error {
+	batch := Session.NewBatch(gocql.LoggedBatch)
+	batch.Query(`DELETE FROM channel_members WHERE channel_id = ? AND user_id = ?`, channelID, userID)
+	batch.Query(`DELETE FROM user_channels WHERE user_id = ? AND channel_id = ?`, userID, channelID)
+	return Session.ExecuteBatch(batch)
+}
+
+// UserChannels returns the ids of the channels userID belongs to
+func UserChannels(userID string) ([]string, error) {
+	iter := Session.Query(`SELECT channel_id FROM user_channels WHERE user_id = ?`, userID).Iter()
+	var channels []string
+	var id string
+	for iter.Scan(&id) {
+		channels = append(channels, id)
+	}
+	return channels, iter.Close()
+}
+
+// IsMember reports whether userID belongs to channelID
+func IsMember(channelID, userID string) (bool, error) {
+	var id string
+	err := Session.Query(`SELECT user_id FROM channel_members WHERE channel_id = ? AND user_id = ?`, channelID, userID).Scan(&id)
+	if err == gocql.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ChannelMembers returns the ids of the members of channelID
+func ChannelMembers(channelID string) ([]string, error) {
+	iter := Session.Query(`SELECT user_id FROM channel_members WHERE channel_id = ?`, channelID).Iter()
+	var members []string
+	var id string
+	for iter.Scan(&id) {
+		members = append(members, id)
+	}
+	return members, iter.Close()
+}
@@ -3,47 +3,28 @@ package db
 import (
 	"fmt"
 	"log"
-	"os"
+	"shared/store"
 
 	"github.com/gocql/gocql"
 )
 
 var Session *gocql.Session
 
-func ConnectCassandra() {
-	host := os.Getenv("CASSANDRA_HOST")
-	keyspace := os.Getenv("CASSANDRA_KEYSPACE") // e.g., "auth" or a new one
-
-	if host == "" {
-		host = "127.0.0.1"
-	}
-	if keyspace == "" {
-		keyspace = "chat"
-	}
+// Keyspace is the keyspace the session is bound to
+var Keyspace string
 
-	// 1. Connect to system to create keyspace
-	cluster := gocql.NewCluster(host)
-	cluster.Consistency = gocql.Quorum
-	
-	// Create temporary session
-	tempSession, err := cluster.CreateSession()
-	if err != nil {
-		log.Fatal("❌ Failed to connect to Cassandra (System):", err)
-	}
-	
-	query := fmt.Sprintf(`CREATE KEYSPACE IF NOT EXISTS %s WITH replication = {'class': 'SimpleStrategy', 'replication_factor': 1};`, keyspace)
-	if err := tempSession.Query(query).Exec(); err != nil {
-		log.Fatalf("❌ Failed to create keyspace '%s': %v", keyspace, err)
-	}
-	tempSession.Close()
+func ConnectCassandra() {
+	// The chat keyspace is created on first start
+	opts := store.CassandraOptionsFromEnv("chat")
+	opts.CreateKeyspace = true
 
-	// 2. Connect to actual keyspace
-	cluster.Keyspace = keyspace
-	Session, err = cluster.CreateSession()
+	var err error
+	Session, err = store.ConnectCassandra(opts)
 	if err != nil {
-		log.Fatal("❌ Failed to connect to Cassandra (Chat Keyspace):", err)
+		log.Fatalf("❌ Failed to connect to Cassandra keyspace '%s': %v", opts.Keyspace, err)
 	}
-	fmt.Printf("✅ Feedback Service: Connected to Keyspace '%s'\n", keyspace)
+	Keyspace = opts.Keyspace
+	fmt.Printf("✅ Feedback Service: Connected to Keyspace '%s'\n", opts.Keyspace)
 }
 
 func Close() {
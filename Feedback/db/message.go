@@ -0,0 +1,168 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Message is a stored chat message
+type Message struct {
+	ID        gocql.UUID `json:"id"`
+	ChannelID string     `json:"channel"`
+	Sender    string     `json:"sender"`
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	CreatedAt time.Time  `json:"created_at"`
+	// EditedBy and EditedAt are set once a moderator changed the content
+	EditedBy string     `json:"edited_by,omitempty"`
+	EditedAt *time.Time `json:"edited_at,omitempty"`
+}
+
+const messageColumns = `id, sender_id, sender_role, content, edited_by, edited_at`
+
+func scanMessage(scan func(...interface{}) bool, m *Message) bool {
+	var edited time.Time
+	if !scan(&m.ID, &m.Sender, &m.Role, &m.Content, &m.EditedBy, &edited) {
+		return false
+	}
+	m.CreatedAt = m.ID.Time()
+	if !edited.IsZero() {
+		m.EditedAt = &edited
+	}
+	return true
+}
+
+// ErrInvalidCursor is returned for a page state ListMessages did not produce
+var ErrInvalidCursor = errors.New("invalid page state")
+
+// DayBucket returns the UTC day partition of t, e.g. "2026-03-04"
+func DayBucket(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// SaveMessage stores a chat message under its id, a TimeUUID, in the partition
+// of the day of that id
+func SaveMessage(m Message) error {
+	day := DayBucket(m.ID.Time())
+	batch := Session.NewBatch(gocql.UnloggedBatch)
+	batch.Query(`INSERT INTO channel_messages (channel_id, day_bucket, id, sender_id, sender_role, content) VALUES (?, ?, ?, ?, ?, ?)`,
+		m.ChannelID, day, m.ID, m.Sender, m.Role, m.Content)
+	batch.Query(`INSERT INTO channel_message_days (channel_id, day_bucket) VALUES (?, ?)`, m.ChannelID, day)
+	return Session.ExecuteBatch(batch)
+}
+
+// GetMessage returns one message of channelID
+func GetMessage(channelID string, id gocql.UUID) (*Message, error) {
+	iter := Session.Query(`SELECT `+messageColumns+` FROM channel_messages WHERE channel_id = ? AND day_bucket = ? AND id = ?`,
+		channelID, DayBucket(id.Time()), id).Iter()
+	m := Message{ChannelID: channelID}
+	found := scanMessage(iter.Scan, &m)
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrMessageNotFound
+	}
+	return &m, nil
+}
+
+// ListMessages returns up to limit messages of channelID sent before before,
+// newest first, and the cursor of the next page (nil when there are no older
+// messages). Later pages must be asked with the same before.
+func ListMessages(channelID string, before time.Time, limit int, cursor []byte) ([]Message, []byte, error) {
+	pager := HistoryPager{
+		ReadDay: func(day string, pageState []byte, size int) ([]Message, []byte, error) {
+			query := `SELECT ` + messageColumns + ` FROM channel_messages
+				WHERE channel_id = ? AND day_bucket = ? AND id < minTimeuuid(?)`
+			iter := Session.Query(query, channelID, day, before).PageSize(size).PageState(pageState).Iter()
+			var messages []Message
+			m := Message{ChannelID: channelID}
+			for scanMessage(iter.Scan, &m) {
+				messages = append(messages, m)
+				m = Message{ChannelID: channelID}
+			}
+			next := iter.PageState()
+			return messages, next, iter.Close()
+		},
+		PreviousDay: func(day string) (string, error) {
+			var previous string
+			err := Session.Query(`SELECT day_bucket FROM channel_message_days WHERE channel_id = ? AND day_bucket < ? LIMIT 1`,
+				channelID, day).Scan(&previous)
+			if err == gocql.ErrNotFound {
+				return "", nil
+			}
+			return previous, err
+		},
+	}
+	return pager.Page(before, limit, cursor)
+}
+
+// HistoryPager pages through the day partitions of one channel, newest first
+type HistoryPager struct {
+	// ReadDay returns up to size messages of day starting at pageState, and
+	// the state of the next page, empty once the day is done
+	ReadDay func(day string, pageState []byte, size int) ([]Message, []byte, error)
+	// PreviousDay returns the latest day with messages before day, or "" when
+	// there is none
+	PreviousDay func(day string) (string, error)
+}
+
+// Page returns up to limit messages from the day of before, or from where
+// cursor stopped, and the cursor of the next page
+func (p HistoryPager) Page(before time.Time, limit int, cursor []byte) ([]Message, []byte, error) {
+	day, pageState := DayBucket(before), []byte(nil)
+	if cursor != nil {
+		var err error
+		if day, pageState, err = DecodeCursor(cursor); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	messages := make([]Message, 0, limit)
+	for {
+		page, next, err := p.ReadDay(day, pageState, limit-len(messages))
+		if err != nil {
+			return nil, nil, err
+		}
+		messages = append(messages, page...)
+		if len(next) > 0 {
+			return messages, EncodeCursor(day, next), nil
+		}
+
+		// This day is done, go on with the previous day that has messages
+		if day, err = p.PreviousDay(day); err != nil {
+			return nil, nil, err
+		}
+		if day == "" {
+			return messages, nil, nil
+		}
+		pageState = nil
+		if len(messages) == limit {
+			return messages, EncodeCursor(day, nil), nil
+		}
+	}
+}
+
+// EncodeCursor returns the cursor of a page: the day being read followed by
+// its Cassandra paging state, empty at the start of the day
+func EncodeCursor(day string, pageState []byte) []byte {
+	return append([]byte(day), pageState...)
+}
+
+// DecodeCursor splits a cursor made by EncodeCursor, or returns ErrInvalidCursor
+func DecodeCursor(cursor []byte) (string, []byte, error) {
+	if len(cursor) < len(time.DateOnly) {
+		return "", nil, ErrInvalidCursor
+	}
+	day := string(cursor[:len(time.DateOnly)])
+	if _, err := time.Parse(time.DateOnly, day); err != nil {
+		return "", nil, ErrInvalidCursor
+	}
+	var pageState []byte
+	if len(cursor) > len(time.DateOnly) {
+		pageState = cursor[len(time.DateOnly):]
+	}
+	return day, pageState, nil
+}
@@ -3,24 +3,144 @@ package db
 import (
 	"fmt"
 	"log"
+	"shared/store"
+	"time"
+
+	"github.com/gocql/gocql"
 )
 
 func CreateMessageTable() {
-	// CLUSTERING ORDER BY created_at ASC allows retrieving messages in chronological order easily
-	query := `
-	CREATE TABLE IF NOT EXISTS messages (
-		channel_id TEXT,
-		id UUID,
-		sender_id TEXT,
-		sender_role TEXT,
-		content TEXT,
-		created_at TIMESTAMP,
-		PRIMARY KEY (channel_id, created_at)
-	) WITH CLUSTERING ORDER BY (created_at ASC);`
-
-	if err := Session.Query(query).Exec(); err != nil {
-		log.Printf("❌ Error creating messages table: %v", err)
+	queries := []string{
+		// One partition per channel and UTC day; the timeuuid id orders messages
+		// newest first and never collides, unlike a timestamp
+		`CREATE TABLE IF NOT EXISTS channel_messages (
+			channel_id TEXT,
+			day_bucket TEXT,
+			id TIMEUUID,
+			sender_id TEXT,
+			sender_role TEXT,
+			content TEXT,
+			edited_by TEXT,
+			edited_at TIMESTAMP,
+			PRIMARY KEY ((channel_id, day_bucket), id)
+		) WITH CLUSTERING ORDER BY (id DESC);`,
+		// The days a channel has messages on, to page through history without
+		// visiting empty days
+		`CREATE TABLE IF NOT EXISTS channel_message_days (
+			channel_id TEXT,
+			day_bucket TEXT,
+			PRIMARY KEY (channel_id, day_bucket)
+		) WITH CLUSTERING ORDER BY (day_bucket DESC);`,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			name TEXT,
+			applied_at TIMESTAMP
+		);`,
+	}
+	for _, query := range queries {
+		if err := Session.Query(query).Exec(); err != nil {
+			log.Printf("❌ Error creating messages table: %v", err)
+			return
+		}
+	}
+	// Tables created before moderators could edit messages
+	if _, err := store.EnsureColumns(Session, Keyspace, "channel_messages", [][2]string{
+		{"edited_by", "TEXT"},
+		{"edited_at", "TIMESTAMP"},
+	}); err != nil {
+		log.Printf("❌ Error migrating messages table: %v", err)
 		return
 	}
 	fmt.Println("✅ Messages table is ready")
 }
+
+// Migration is one versioned change to existing data. Up must be safe to run
+// again: two instances starting together may both run it before either
+// records it.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func() error
+}
+
+// Migrations run in order of version, once each
+var Migrations = []Migration{
+	{Version: 1, Name: "copy messages into channel_messages", Up: copyLegacyMessages},
+}
+
+// Migrate runs the migrations not yet recorded in schema_migrations. Call it
+// after the tables are created.
+func Migrate() error {
+	applied := make(map[int]bool)
+	iter := Session.Query(`SELECT version FROM schema_migrations`).Iter()
+	var version int
+	for iter.Scan(&version) {
+		applied[version] = true
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	for _, m := range Migrations {
+		if applied[m.Version] {
+			continue
+		}
+		fmt.Printf("🔄 Running migration %d: %s\n", m.Version, m.Name)
+		if err := m.Up(); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		if err := Session.Query(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			m.Version, m.Name, time.Now()).Exec(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyLegacyMessages copies the rows of the old messages table, keyed by
+// (channel_id, created_at), into channel_messages. The old table is left in
+// place and can be dropped once the copy is checked.
+func copyLegacyMessages() error {
+	var table string
+	err := Session.Query(`SELECT table_name FROM system_schema.tables WHERE keyspace_name = ? AND table_name = 'messages'`, Keyspace).Scan(&table)
+	if err == gocql.ErrNotFound {
+		return nil // a new install never had it
+	}
+	if err != nil {
+		return err
+	}
+
+	iter := Session.Query(`SELECT channel_id, id, sender_id, sender_role, content, created_at FROM messages`).PageSize(500).Iter()
+	var (
+		m       Message
+		copied  int
+		created time.Time
+	)
+	for iter.Scan(&m.ChannelID, &m.ID, &m.Sender, &m.Role, &m.Content, &created) {
+		// Messages were stored with a TimeUUID taken just before created_at;
+		// keeping it, or deriving one from the old id, makes the copy idempotent
+		if m.ID.Version() != 1 {
+			m.ID = LegacyMessageID(m.ID, created)
+		}
+		if err := SaveMessage(m); err != nil {
+			iter.Close()
+			return err
+		}
+		copied++
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Copied %d messages into channel_messages\n", copied)
+	return nil
+}
+
+// LegacyMessageID returns the TimeUUID a legacy message with a non-time id is
+// copied under: the time of created with the clock sequence and node of old,
+// so copying the same row again gives the same id
+func LegacyMessageID(old gocql.UUID, created time.Time) gocql.UUID {
+	id := gocql.UUIDFromTime(created)
+	copy(id[8:], old[8:])
+	id[8] = id[8]&0x3f | 0x80 // RFC 4122 variant
+	return id
+}
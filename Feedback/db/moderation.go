@@ -0,0 +1,126 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Channel modes
+const (
+	ModeOpen = "open"
+	// ModeReadOnly lets nobody post
+	ModeReadOnly = "read_only"
+	// ModeModerators lets only users with chat:moderate post
+	ModeModerators = "moderators_only"
+	// ModeSlow lets each user post once every SlowSeconds
+	ModeSlow = "slow"
+)
+
+// Sanction kinds
+const (
+	SanctionMute = "mute"
+	SanctionBan  = "ban"
+)
+
+// ErrMessageNotFound is returned when editing or deleting a missing message
+var ErrMessageNotFound = errors.New("message not found")
+
+// Sanction keeps a user from posting in (mute) or joining (ban) a channel
+type Sanction struct {
+	ChannelID string     `json:"channel"`
+	UserID    string     `json:"user_id"`
+	Kind      string     `json:"kind"`
+	Reason    string     `json:"reason,omitempty"`
+	CreatedBy string     `json:"created_by"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil until lifted
+}
+
+func CreateSanctionTable() {
+	query := `CREATE TABLE IF NOT EXISTS channel_sanctions (
+		channel_id TEXT,
+		user_id TEXT,
+		kind TEXT,
+		reason TEXT,
+		created_by TEXT,
+		expires_at TIMESTAMP,
+		PRIMARY KEY (channel_id, user_id, kind)
+	);`
+	if err := Session.Query(query).Exec(); err != nil {
+		log.Printf("❌ Error creating channel_sanctions table: %v", err)
+		return
+	}
+	fmt.Println("✅ Sanctions table is ready")
+}
+
+// SetChannelMode stores the mode of a channel, creating the row of the global
+// channel the first time
+func SetChannelMode(channelID, kind, mode string, slowSeconds int) error {
+	return Session.Query(`UPDATE channels SET kind = ?, mode = ?, slow_seconds = ? WHERE id = ?`,
+		kind, mode, slowSeconds, channelID).Exec()
+}
+
+// AddSanction stores s. It expires by itself at s.ExpiresAt.
+func AddSanction(s Sanction) error {
+	ttl := 0 // no expiry
+	if s.ExpiresAt != nil {
+		ttl = max(int(time.Until(*s.ExpiresAt).Seconds()), 1)
+	}
+	return Session.Query(`INSERT INTO channel_sanctions (channel_id, user_id, kind, reason, created_by, expires_at) VALUES (?, ?, ?, ?, ?, ?) USING TTL ?`,
+		s.ChannelID, s.UserID, s.Kind, s.Reason, s.CreatedBy, s.ExpiresAt, ttl).Exec()
+}
+
+// RemoveSanction lifts a mute or ban early
+func RemoveSanction(channelID, userID, kind string) error {
+	return Session.Query(`DELETE FROM channel_sanctions WHERE channel_id = ? AND user_id = ? AND kind = ?`,
+		channelID, userID, kind).Exec()
+}
+
+// ActiveSanctions returns the sanctions of userID in channelID by kind
+func ActiveSanctions(channelID, userID string) (map[string]Sanction, error) {
+	iter := Session.Query(`SELECT kind, reason, created_by, expires_at FROM channel_sanctions WHERE channel_id = ? AND user_id = ?`,
+		channelID, userID).Iter()
+	sanctions := make(map[string]Sanction)
+	s := Sanction{ChannelID: channelID, UserID: userID}
+	var expires time.Time
+	for iter.Scan(&s.Kind, &s.Reason, &s.CreatedBy, &expires) {
+		if !expires.IsZero() {
+			at := expires
+			s.ExpiresAt = &at
+		}
+		sanctions[s.Kind] = s
+		s = Sanction{ChannelID: channelID, UserID: userID}
+		expires = time.Time{}
+	}
+	return sanctions, iter.Close()
+}
+
+// EditMessage replaces the content of a message, recording who edited it
+func EditMessage(channelID string, id gocql.UUID, content, editedBy string) (*Message, error) {
+	now := time.Now()
+	applied, err := Session.Query(`UPDATE channel_messages SET content = ?, edited_by = ?, edited_at = ? WHERE channel_id = ? AND day_bucket = ? AND id = ? IF EXISTS`,
+		content, editedBy, now, channelID, DayBucket(id.Time()), id).ScanCAS()
+	if err != nil {
+		return nil, err
+	}
+	if !applied {
+		return nil, ErrMessageNotFound
+	}
+	return GetMessage(channelID, id)
+}
+
+// DeleteMessage removes a message from the history
+func DeleteMessage(channelID string, id gocql.UUID) error {
+	applied, err := Session.Query(`DELETE FROM channel_messages WHERE channel_id = ? AND day_bucket = ? AND id = ? IF EXISTS`,
+		channelID, DayBucket(id.Time()), id).ScanCAS()
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return ErrMessageNotFound
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"Feedback/routes"
+	"Feedback/utils"
 	"fmt"
 	"log"
 	"net/http"
@@ -23,26 +24,43 @@ func main() {
 		port = "8081"
 	}
 
-	// 2. Connect DB
+	// 2. Token verification (public keys from the Auth service, revocations in Redis)
+	utils.ConnectRedis()
+	utils.InitVerifier()
+
+	// 3. Connect DB
 	db.ConnectCassandra()
 	defer db.Close()
 	db.CreateMessageTable()
+	db.CreateChannelTables()
+	db.CreateSanctionTable()
+	if err := db.Migrate(); err != nil {
+		log.Fatalf("❌ Failed to migrate the chat schema: %v", err)
+	}
 
-	// 3. Start Both Hubs in Background
+	// 4. Start Both Hubs in Background
 	go routes.C_Hub.Run() // Chat Hub
 	go routes.N_Hub.Run() // Notification Hub
 
-	// 4. Define Routes
+	// 5. Share both hubs with the other instances through Redis
+	go routes.ListenToPeers()
+	go routes.KeepPresence()
+
+	// 6. Define Routes
 	// -> Staff/Admin connect here to chat
 	http.HandleFunc("/ws/chat", routes.ChatHandler)
 	
 	// -> Users connect here to receive alerts
 	http.HandleFunc("/ws/notifications", routes.NotificationHandler)
 
+	// -> Chat history of a channel (Bearer token)
+	http.HandleFunc("GET /api/v0/chat/{channel}/messages", routes.ChannelMessages)
+	http.HandleFunc("GET /api/v0/chat/{channel}/presence", routes.ChannelPresence)
+
 	// -> Internal Microservices hit this to trigger alerts
 	http.HandleFunc("/internal/notify", routes.TriggerNotificationHandler)
 
-	// 5. Start Server
+	// 7. Start Server
 	fmt.Printf("Feedback Service started on :%s\n", port)
 	fmt.Printf(" - Chat: ws://localhost:%s/ws/chat\n", port)
 	fmt.Printf(" - Notif: ws://localhost:%s/ws/notifications\n", port)
@@ -0,0 +1,127 @@
+package routes
+
+import (
+	"Feedback/db"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"shared/auth"
+	"sort"
+	"strings"
+	"time"
+)
+
+// globalChannel is the room every chat user is in
+const globalChannel = "global"
+
+// channelName is the <name> of line:<name> and shift:<name>
+var channelName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// ResolveChannel checks a channel id sent by userID and returns it in canonical
+// form with its kind. An empty id is the global channel and "dm:<user id>" is
+// the direct channel with that user, stored as dm:<lower id>:<higher id>.
+func ResolveChannel(id, userID string) (string, string, error) {
+	if id == "" || id == globalChannel {
+		return globalChannel, "global", nil
+	}
+	kind, name, _ := strings.Cut(id, ":")
+	switch kind {
+	case "line", "shift":
+		if !channelName.MatchString(name) {
+			return "", "", fmt.Errorf("%s names are lower case letters, digits, - and _", kind)
+		}
+		return id, kind, nil
+	case "dm":
+		users := strings.Split(name, ":")
+		if len(users) == 1 {
+			users = append(users, userID)
+		}
+		if len(users) != 2 || users[0] == "" || users[1] == "" || users[0] == users[1] {
+			return "", "", errors.New("a direct channel is dm:<user id> of another user")
+		}
+		if users[0] != userID && users[1] != userID {
+			return "", "", errors.New("not your direct channel")
+		}
+		sort.Strings(users)
+		return "dm:" + users[0] + ":" + users[1], "direct", nil
+	}
+	return "", "", errors.New("channels are global, line:<name>, shift:<name> or dm:<user id>")
+}
+
+// joinChannel adds the client's user to a channel. Line and shift channels are
+// created by the first moderator who joins them; anyone who may write can open
+// a direct channel, which adds the other user as well.
+func joinChannel(client *ChatClient, claims *auth.Claims, id string) {
+	channel, kind, err := ResolveChannel(id, claims.UserID)
+	if err != nil {
+		C_Hub.Reply(client, ChatMsg{Type: "error", Channel: id, Content: err.Error()})
+		return
+	}
+	if channel == globalChannel {
+		C_Hub.Reply(client, ChatMsg{Type: "joined", Channel: channel})
+		return
+	}
+
+	existing, err := db.GetChannel(channel)
+	if err != nil {
+		log.Printf("❌ Failed to load channel %s: %v", channel, err)
+		C_Hub.Reply(client, ChatMsg{Type: "error", Channel: channel, Content: "Failed to join the channel"})
+		return
+	}
+	sanctions, err := db.ActiveSanctions(channel, claims.UserID)
+	if err != nil {
+		log.Printf("❌ Failed to load sanctions of %s in %s: %v", claims.UserID, channel, err)
+		C_Hub.Reply(client, ChatMsg{Type: "error", Channel: channel, Content: "Failed to join the channel"})
+		return
+	}
+	if ban, ok := sanctions[db.SanctionBan]; ok {
+		C_Hub.Reply(client, ChatMsg{Type: "error", Channel: channel, Content: "You are banned from this channel" + until(ban.ExpiresAt)})
+		return
+	}
+
+	members := []string{claims.UserID}
+	if existing == nil {
+		allowed := claims.Can("chat:moderate")
+		if kind == "direct" {
+			allowed = claims.Can("chat:write")
+			members = strings.Split(strings.TrimPrefix(channel, "dm:"), ":")
+		}
+		if !allowed {
+			C_Hub.Reply(client, ChatMsg{Type: "error", Channel: channel, Content: "No such channel"})
+			return
+		}
+		if err := db.CreateChannel(db.Channel{ID: channel, Kind: kind, CreatedBy: claims.UserID, CreatedAt: time.Now()}); err != nil {
+			log.Printf("❌ Failed to create channel %s: %v", channel, err)
+			C_Hub.Reply(client, ChatMsg{Type: "error", Channel: channel, Content: "Failed to join the channel"})
+			return
+		}
+	}
+
+	for _, user := range members {
+		if err := db.AddMember(channel, user); err != nil {
+			log.Printf("❌ Failed to add %s to channel %s: %v", user, channel, err)
+			C_Hub.Reply(client, ChatMsg{Type: "error", Channel: channel, Content: "Failed to join the channel"})
+			return
+		}
+		C_Hub.PublishMembership(Membership{UserID: user, Channel: channel, Join: true})
+	}
+}
+
+// leaveChannel takes the client's user out of a channel on every device
+func leaveChannel(client *ChatClient, userID, id string) {
+	channel, _, err := ResolveChannel(id, userID)
+	if err == nil && channel == globalChannel {
+		err = errors.New("everyone stays in the global channel")
+	}
+	if err != nil {
+		C_Hub.Reply(client, ChatMsg{Type: "error", Channel: id, Content: err.Error()})
+		return
+	}
+	if err := db.RemoveMember(channel, userID); err != nil {
+		log.Printf("❌ Failed to remove %s from channel %s: %v", userID, channel, err)
+		C_Hub.Reply(client, ChatMsg{Type: "error", Channel: channel, Content: "Failed to leave the channel"})
+		return
+	}
+	C_Hub.PublishMembership(Membership{UserID: userID, Channel: channel, Join: false})
+}
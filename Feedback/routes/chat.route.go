@@ -3,11 +3,12 @@ package routes
 import (
 	"Feedback/utils"
 	"encoding/json"
+	"log"
 	"net/http"
+	"shared/auth"
 	"sync"
 
 	"Feedback/db"
-	"time"
 
 	"github.com/gocql/gocql"
 	"github.com/gorilla/websocket"
@@ -15,79 +16,196 @@ import (
 
 // --- CHAT TYPES ---
 type ChatMsg struct {
-	Type    string `json:"type"`    // "msg" or "cmd" (open/close)
+	Type    string `json:"type"`    // "msg", "join", "leave" or a moderation command (see moderate); the server also sends "channels", "history", "joined", "left", "system" and "error"
+	ID      string `json:"id,omitempty"`      // set by the server, a TimeUUID
+	Ref     string `json:"ref,omitempty"`     // chosen by the sender and echoed back, to match errors to messages
+	Channel string `json:"channel,omitempty"` // "global" when empty
 	Content string `json:"content"`
 	Sender  string `json:"sender"`  // UserID
 	Role    string `json:"role"`    // Admin/Staff
+	// Channels lists the user's channels in the "channels" message sent on connect
+	Channels []string `json:"channels,omitempty"`
+	// History holds a channel's latest messages, newest first, in "history" messages
+	History []db.Message `json:"history,omitempty"`
+	// Moderation commands and the "system" messages describing them
+	Action  string      `json:"action,omitempty"`  // what changed: mode, mute, unmute, ban, unban, kick, edit or delete
+	Mode    string      `json:"mode,omitempty"`    // open, read_only, moderators_only or slow
+	Seconds int         `json:"seconds,omitempty"` // slow mode interval, or how long a mute or ban lasts (0 until lifted)
+	Target  string      `json:"target,omitempty"`  // UserID of a mute, ban or kick
+	Message *db.Message `json:"message,omitempty"` // the message after an edit
 }
 
 type ChatClient struct {
-	Conn *websocket.Conn
-	Send chan []byte
-	Role string
+	Conn   *websocket.Conn
+	Send   chan []byte
+	Role   string
+	UserID string
+	// Rooms are the channels this connection receives, guarded by the hub's Mu
+	Rooms map[string]bool
+}
+
+// Membership joins or leaves Channel on every connection of UserID
+type Membership struct {
+	UserID  string `json:"user_id"`
+	Channel string `json:"channel"`
+	Join    bool   `json:"join"`
 }
 
 type ChatHub struct {
-	Clients     map[*ChatClient]bool
-	Broadcast   chan ChatMsg
-	Register    chan *ChatClient
-	Unregister  chan *ChatClient
-	ChatAllowed bool
-	Mu          sync.Mutex
+	Clients    map[*ChatClient]bool
+	Rooms      map[string]map[*ChatClient]bool // MAP: Channel -> members connected here
+	Users      map[string]map[*ChatClient]bool // MAP: UserID -> connections
+	Broadcast  chan ChatMsg
+	Register   chan *ChatClient
+	Unregister chan *ChatClient
+	Membership chan Membership
+	Mu         sync.Mutex
 }
 
-var C_Hub = &ChatHub{
-	Clients:     make(map[*ChatClient]bool),
-	Broadcast:   make(chan ChatMsg),
-	Register:    make(chan *ChatClient),
-	Unregister:  make(chan *ChatClient),
-	ChatAllowed: false, // Default closed
+var C_Hub = NewChatHub()
+
+// NewChatHub returns an empty hub; call Run to start it
+func NewChatHub() *ChatHub {
+	return &ChatHub{
+		Clients:    make(map[*ChatClient]bool),
+		Rooms:      make(map[string]map[*ChatClient]bool),
+		Users:      make(map[string]map[*ChatClient]bool),
+		Broadcast:  make(chan ChatMsg),
+		Register:   make(chan *ChatClient),
+		Unregister: make(chan *ChatClient),
+		Membership: make(chan Membership),
+	}
 }
 
 func (h *ChatHub) Run() {
 	for {
 		select {
 		case client := <-h.Register:
-			h.Mu.Lock(); h.Clients[client] = true; h.Mu.Unlock()
-		case client := <-h.Unregister:
 			h.Mu.Lock()
-			if _, ok := h.Clients[client]; ok {
-				delete(h.Clients, client)
-				close(client.Send)
+			h.Clients[client] = true
+			index(h.Users, client.UserID, client)
+			for room := range client.Rooms {
+				index(h.Rooms, room, client)
 			}
 			h.Mu.Unlock()
-		case msg := <-h.Broadcast:
+		case client := <-h.Unregister:
 			h.Mu.Lock()
-			
-			// 1. Logic: Admin Commands
-			if msg.Role == "admin" && msg.Type == "cmd" {
-				if msg.Content == "open" { h.ChatAllowed = true }
-				if msg.Content == "close" { h.ChatAllowed = false }
-			}
-
-			// 2. Logic: Permission Check
-			shouldSend := true
-			if msg.Role == "staff" && !h.ChatAllowed {
-				shouldSend = false
+			h.drop(client)
+			h.Mu.Unlock()
+		case m := <-h.Membership:
+			h.Mu.Lock()
+			event := ChatMsg{Type: "left", Channel: m.Channel}
+			if m.Join {
+				event.Type = "joined"
 			}
-
-			// 3. Logic: Broadcast
-			if shouldSend {
-				bytes, _ := json.Marshal(msg)
-				for client := range h.Clients {
-					// Send to everyone (or add specific filtering here)
-					select {
-					case client.Send <- bytes:
-					default:
-						close(client.Send); delete(h.Clients, client)
-					}
+			bytes, _ := json.Marshal(event)
+			for client := range h.Users[m.UserID] {
+				if m.Join {
+					client.Rooms[m.Channel] = true
+					index(h.Rooms, m.Channel, client)
+				} else {
+					delete(client.Rooms, m.Channel)
+					unindex(h.Rooms, m.Channel, client)
 				}
+				h.send(client, bytes)
+			}
+			h.Mu.Unlock()
+		case msg := <-h.Broadcast:
+			// Messages were checked against the channel's mode by the sender's
+			// read pump, they go to the members of the channel
+			h.Mu.Lock()
+			bytes, _ := json.Marshal(msg)
+			for client := range h.Rooms[msg.Channel] {
+				h.send(client, bytes)
 			}
 			h.Mu.Unlock()
 		}
 	}
 }
 
+// Publish delivers msg to the members of its channel on every instance
+func (h *ChatHub) Publish(msg ChatMsg) {
+	h.Broadcast <- msg
+	publish(chatTopic, peerEvent{Msg: &msg})
+}
+
+// PublishMembership applies m to the user's connections on every instance
+func (h *ChatHub) PublishMembership(m Membership) {
+	h.Membership <- m
+	publish(chatTopic, peerEvent{Membership: &m})
+}
+
+// ConnectedUsers returns the users with a connection to this instance
+func (h *ChatHub) ConnectedUsers() []string {
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+	users := make([]string, 0, len(h.Users))
+	for user := range h.Users {
+		users = append(users, user)
+	}
+	return users
+}
+
+// Connected reports whether userID still has a connection to this instance
+func (h *ChatHub) Connected(userID string) bool {
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+	return len(h.Users[userID]) > 0
+}
+
+// Reply sends msg to one client, unless it has disconnected
+func (h *ChatHub) Reply(client *ChatClient, msg ChatMsg) {
+	bytes, _ := json.Marshal(msg)
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+	if h.Clients[client] {
+		h.send(client, bytes)
+	}
+}
+
+// InRoom reports whether client receives channel
+func (h *ChatHub) InRoom(client *ChatClient, channel string) bool {
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+	return client.Rooms[channel]
+}
+
+// send queues bytes for client and drops clients that are not keeping up. Call
+// it with Mu held.
+func (h *ChatHub) send(client *ChatClient, bytes []byte) {
+	select {
+	case client.Send <- bytes:
+	default:
+		h.drop(client)
+	}
+}
+
+func (h *ChatHub) drop(client *ChatClient) {
+	if _, ok := h.Clients[client]; !ok {
+		return
+	}
+	delete(h.Clients, client)
+	unindex(h.Users, client.UserID, client)
+	for room := range client.Rooms {
+		unindex(h.Rooms, room, client)
+	}
+	close(client.Send)
+}
+
+func index(m map[string]map[*ChatClient]bool, key string, client *ChatClient) {
+	if m[key] == nil {
+		m[key] = make(map[*ChatClient]bool)
+	}
+	m[key][client] = true
+}
+
+func unindex(m map[string]map[*ChatClient]bool, key string, client *ChatClient) {
+	delete(m[key], client)
+	if len(m[key]) == 0 {
+		delete(m, key)
+	}
+}
+
 // --- HANDLER ---
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
@@ -98,24 +216,37 @@ var upgrader = websocket.Upgrader{
 func ChatHandler(w http.ResponseWriter, r *http.Request) {
 	token := r.URL.Query().Get("token")
 	claims, err := utils.ParseToken(token)
-	if err != nil { http.Error(w, "Unauthorized", 401); return }
+	if err != nil { auth.WriteUnauthorized(w, err); return }
 
-	// 🔒 Validate Login Status (Cross-Keyspace Query)
-	userID := claims["user_id"].(string)
-	var isLoggedIn bool
-	if err := db.Session.Query(`SELECT isloggedin FROM auth.users WHERE id = ?`, userID).Scan(&isLoggedIn); err != nil {
-		http.Error(w, "User not found", 401)
+	if !claims.Can("chat:read") {
+		http.Error(w, "Forbidden", 403)
 		return
 	}
-	if !isLoggedIn {
-		http.Error(w, "User is not logged in", 403)
+	canWrite := claims.Can("chat:write")
+
+	// The user gets the traffic of the channels they joined before, and global
+	channels, err := db.UserChannels(claims.UserID)
+	if err != nil {
+		log.Printf("❌ Failed to load channels of user %s: %v", claims.UserID, err)
+		http.Error(w, "Chat is unavailable", 503)
 		return
 	}
+	rooms := map[string]bool{globalChannel: true}
+	for _, channel := range channels {
+		rooms[channel] = true
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil { return }
+	client := &ChatClient{Conn: conn, Send: make(chan []byte, 256), Role: claims.Role, UserID: claims.UserID, Rooms: rooms}
 
-	conn, _ := upgrader.Upgrade(w, r, nil)
-	client := &ChatClient{Conn: conn, Send: make(chan []byte, 256), Role: claims["role"].(string)}
-	
 	C_Hub.Register <- client
+	if err := utils.MarkOnline(claims.UserID); err != nil {
+		log.Printf("⚠️ Failed to mark %s online: %v", claims.UserID, err)
+	}
+	channels = append([]string{globalChannel}, channels...)
+	C_Hub.Reply(client, ChatMsg{Type: "channels", Channels: channels})
+	go sendHistory(client, channels)
 
 	// Write Pump (Inline for brevity)
 	go func() {
@@ -125,30 +256,56 @@ func ChatHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Read Pump
 	go func() {
-		defer func() { C_Hub.Unregister <- client; conn.Close() }()
+		defer func() {
+			C_Hub.Unregister <- client
+			conn.Close()
+			if !C_Hub.Connected(claims.UserID) {
+				if err := utils.MarkOffline(claims.UserID); err != nil {
+					log.Printf("⚠️ Failed to mark %s offline: %v", claims.UserID, err)
+				}
+			}
+		}()
 		for {
 			_, bytes, err := conn.ReadMessage()
 			if err != nil { break }
 			var msg ChatMsg
-			if json.Unmarshal(bytes, &msg) == nil {
-				msg.Sender = claims["user_id"].(string)
-				msg.Role = claims["role"].(string)
-
-				// Async Save to DB
-				go func(m ChatMsg) {
-					// Use specific channel ID logic if needed, defaulting to "global"
-					channelID := "global"
-					logID := gocql.TimeUUID()
-					
-					query := `INSERT INTO messages (channel_id, id, sender_id, sender_role, content, created_at) VALUES (?, ?, ?, ?, ?, ?)`
-					if err := db.Session.Query(query, channelID, logID, m.Sender, m.Role, m.Content, time.Now()).Exec(); err != nil {
-						// log error but don't stop broadcast
-						// fmt.Println("Error saving message:", err)
-					}
-				}(msg)
-
-				C_Hub.Broadcast <- msg
+			if json.Unmarshal(bytes, &msg) != nil {
+				continue
+			}
+			switch msg.Type {
+			case "join":
+				joinChannel(client, claims, msg.Channel)
+			case "leave":
+				leaveChannel(client, claims.UserID, msg.Channel)
+			case "cmd", "mode", "mute", "unmute", "ban", "unban", "kick", "edit", "delete":
+				moderate(client, claims, msg)
+			case "msg":
+				if !canWrite {
+					C_Hub.Reply(client, ChatMsg{Type: "error", Ref: msg.Ref, Channel: msg.Channel, Content: "You may not write in chat"})
+					continue
+				}
+				channel, _, err := ResolveChannel(msg.Channel, claims.UserID)
+				if err != nil || !C_Hub.InRoom(client, channel) {
+					C_Hub.Reply(client, ChatMsg{Type: "error", Ref: msg.Ref, Channel: msg.Channel, Content: "Join the channel first"})
+					continue
+				}
+				if err := checkPost(claims, channel); err != nil {
+					C_Hub.Reply(client, ChatMsg{Type: "error", Ref: msg.Ref, Channel: channel, Content: err.Error()})
+					continue
+				}
+				id := gocql.TimeUUID()
+				msg = ChatMsg{Type: "msg", ID: id.String(), Ref: msg.Ref, Channel: channel, Content: msg.Content, Sender: claims.UserID, Role: claims.Role}
+
+				// Save before broadcasting, so nobody sees a message that is not
+				// in the history; the sender learns when it was not delivered
+				if err := db.SaveMessage(db.Message{ID: id, ChannelID: channel, Sender: msg.Sender, Role: msg.Role, Content: msg.Content}); err != nil {
+					log.Printf("❌ Error saving message to %s: %v", channel, err)
+					C_Hub.Reply(client, ChatMsg{Type: "error", Ref: msg.Ref, Channel: channel, Content: "Message not sent, please try again"})
+					continue
+				}
+
+				C_Hub.Publish(msg)
 			}
 		}
 	}()
-}
\ No newline at end of file
+}
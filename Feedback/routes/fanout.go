@@ -0,0 +1,72 @@
+package routes
+
+import (
+	"Feedback/utils"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// Redis pub/sub channels shared by every Feedback instance. Each instance
+// delivers what it publishes itself right away and what others publish when
+// it arrives, so users see the same traffic whichever instance they reach.
+const (
+	chatTopic  = "feedback:chat"
+	notifTopic = "feedback:notify"
+)
+
+// peerEvent is what instances publish to each other
+type peerEvent struct {
+	Origin       string        `json:"origin"`
+	Msg          *ChatMsg      `json:"msg,omitempty"`
+	Membership   *Membership   `json:"membership,omitempty"`
+	Notification *Notification `json:"notification,omitempty"`
+}
+
+// publish sends event to the other instances. Pub/sub does not keep messages:
+// an instance that is disconnected from Redis misses them, and its chat
+// clients catch up from the history when they reconnect.
+func publish(topic string, event peerEvent) {
+	event.Origin = utils.InstanceID
+	payload, _ := json.Marshal(event)
+	if err := utils.RDB.Publish(utils.Ctx, topic, payload).Err(); err != nil {
+		log.Printf("⚠️ Failed to publish to other instances on %s: %v", topic, err)
+	}
+}
+
+// ListenToPeers delivers the chat messages, membership changes and
+// notifications published by other instances to the clients connected here
+func ListenToPeers() {
+	sub := utils.RDB.Subscribe(utils.Ctx, chatTopic, notifTopic)
+	defer sub.Close()
+	for m := range sub.Channel() {
+		var event peerEvent
+		if err := json.Unmarshal([]byte(m.Payload), &event); err != nil {
+			log.Printf("⚠️ Ignoring malformed event on %s: %v", m.Channel, err)
+			continue
+		}
+		if event.Origin == utils.InstanceID {
+			continue // delivered when it was published
+		}
+		switch {
+		case event.Msg != nil:
+			C_Hub.Broadcast <- *event.Msg
+		case event.Membership != nil:
+			C_Hub.Membership <- *event.Membership
+		case event.Notification != nil:
+			N_Hub.Deliver(*event.Notification)
+		}
+	}
+}
+
+// KeepPresence refreshes the presence of the chat users connected here every
+// third of utils.PresenceTTL, so they stay online while this instance runs
+func KeepPresence() {
+	ticker := time.NewTicker(utils.PresenceTTL / 3)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := utils.MarkOnline(C_Hub.ConnectedUsers()...); err != nil {
+			log.Printf("⚠️ Failed to refresh presence: %v", err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package routes
+
+import (
+	"Feedback/db"
+	"Feedback/utils"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"shared/auth"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+// ChannelMessages returns the messages of a channel the caller belongs to,
+// newest first, sent before `before` (RFC 3339, default now). Pass
+// next_page_state back as page_state, with the same before, for older ones.
+func ChannelMessages(w http.ResponseWriter, r *http.Request) {
+	channel, ok := memberChannel(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	var err error
+	limit := defaultHistoryLimit
+	if raw := query.Get("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 || limit > maxHistoryLimit {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be between 1 and 200"})
+			return
+		}
+	}
+	before := time.Now()
+	if raw := query.Get("before"); raw != "" {
+		if before, err = time.Parse(time.RFC3339Nano, raw); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "before must be an RFC 3339 timestamp"})
+			return
+		}
+	}
+	var pageState []byte
+	if raw := query.Get("page_state"); raw != "" {
+		if pageState, err = base64.RawURLEncoding.DecodeString(raw); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid page_state"})
+			return
+		}
+	}
+
+	messages, next, err := db.ListMessages(channel, before, limit, pageState)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid page_state"})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to read messages of %s: %v", channel, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to read messages"})
+		return
+	}
+	response := map[string]interface{}{"channel": channel, "messages": messages, "count": len(messages)}
+	if next != nil {
+		response["next_page_state"] = base64.RawURLEncoding.EncodeToString(next)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// memberChannel verifies the bearer token of r and returns the {channel} of
+// the path when the caller may read chat and belongs to it
+func memberChannel(w http.ResponseWriter, r *http.Request) (string, bool) {
+	claims, err := utils.Verifier.VerifyRequest(r)
+	if err != nil {
+		auth.WriteUnauthorized(w, err)
+		return "", false
+	}
+	if !claims.Can("chat:read") {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Access denied", "permission": "chat:read"})
+		return "", false
+	}
+
+	channel, _, err := ResolveChannel(r.PathValue("channel"), claims.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return "", false
+	}
+	if channel == globalChannel {
+		return channel, true
+	}
+	member, err := db.IsMember(channel, claims.UserID)
+	if err != nil {
+		log.Printf("❌ Failed to check membership of %s in %s: %v", claims.UserID, channel, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to check channel membership"})
+		return "", false
+	}
+	if !member {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Not a member of this channel"})
+		return "", false
+	}
+	return channel, true
+}
+
+// sendHistory sends the client the last CHAT_HISTORY_MESSAGES (default 50, 0
+// to turn off) messages of each channel, as one "history" message per channel
+func sendHistory(client *ChatClient, channels []string) {
+	limit := defaultHistoryLimit
+	if n, err := strconv.Atoi(os.Getenv("CHAT_HISTORY_MESSAGES")); err == nil && n >= 0 {
+		limit = min(n, maxHistoryLimit)
+	}
+	if limit == 0 {
+		return
+	}
+	now := time.Now()
+	for _, channel := range channels {
+		messages, _, err := db.ListMessages(channel, now, limit, nil)
+		if err != nil {
+			log.Printf("⚠️ Failed to load history of %s: %v", channel, err)
+			continue
+		}
+		if len(messages) > 0 {
+			C_Hub.Reply(client, ChatMsg{Type: "history", Channel: channel, History: messages})
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,243 @@
+package routes
+
+import (
+	"Feedback/db"
+	"Feedback/utils"
+	"errors"
+	"fmt"
+	"log"
+	"shared/auth"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+const maxSlowSeconds = 3600
+
+var errUnavailable = errors.New("Chat is unavailable, please try again")
+
+// channelMode returns the mode of a channel and its slow mode interval. The
+// global channel is for moderators until one opens it, like the old closed chat.
+func channelMode(channel string) (string, int, error) {
+	ch, err := db.GetChannel(channel)
+	if err != nil {
+		return "", 0, err
+	}
+	if ch == nil || ch.Mode == "" {
+		if channel == globalChannel {
+			return db.ModeModerators, 0, nil
+		}
+		return db.ModeOpen, 0, nil
+	}
+	return ch.Mode, ch.SlowSeconds, nil
+}
+
+// checkPost returns why the user of claims may not post in channel right now,
+// or nil. In slow mode a nil answer uses up the user's turn.
+func checkPost(claims *auth.Claims, channel string) error {
+	sanctions, err := db.ActiveSanctions(channel, claims.UserID)
+	if err != nil {
+		log.Printf("❌ Failed to load sanctions of %s in %s: %v", claims.UserID, channel, err)
+		return errUnavailable
+	}
+	mode, slowSeconds, err := channelMode(channel)
+	if err != nil {
+		log.Printf("❌ Failed to load mode of %s: %v", channel, err)
+		return errUnavailable
+	}
+	moderator := claims.Can("chat:moderate")
+	if err := PostRule(mode, moderator, sanctions); err != nil || mode != db.ModeSlow || moderator {
+		return err
+	}
+
+	wait, err := utils.SlowModeWait(channel, claims.UserID, time.Duration(slowSeconds)*time.Second)
+	if err != nil {
+		// Better a fast message than none
+		log.Printf("⚠️ Slow mode of %s not enforced: %v", channel, err)
+		return nil
+	}
+	if wait > 0 {
+		return fmt.Errorf("Slow mode is on, wait %d more seconds", int(wait.Round(time.Second).Seconds()))
+	}
+	return nil
+}
+
+// PostRule returns why a user with sanctions may not post in a channel of
+// mode, or nil. The slow mode interval is left to the caller.
+func PostRule(mode string, moderator bool, sanctions map[string]db.Sanction) error {
+	if mute, ok := sanctions[db.SanctionMute]; ok {
+		return fmt.Errorf("You are muted in this channel%s", until(mute.ExpiresAt))
+	}
+	switch mode {
+	case db.ModeReadOnly:
+		return errors.New("This channel is read-only")
+	case db.ModeModerators:
+		if !moderator {
+			return errors.New("Only moderators can post in this channel")
+		}
+	}
+	return nil
+}
+
+// invalidCommand is a moderation command that cannot be applied as sent
+type invalidCommand string
+
+func (e invalidCommand) Error() string { return string(e) }
+
+// pastTense names a sanction in system messages
+var pastTense = map[string]string{"mute": "muted", "unmute": "unmuted", "ban": "banned", "unban": "unbanned"}
+
+// moderate applies a moderation command of a moderator who is in msg's
+// channel and tells the channel what changed with a "system" message
+func moderate(client *ChatClient, claims *auth.Claims, msg ChatMsg) {
+	if !claims.Can("chat:moderate") {
+		C_Hub.Reply(client, ChatMsg{Type: "error", Ref: msg.Ref, Channel: msg.Channel, Content: "Only moderators can do this"})
+		return
+	}
+	channel, kind, err := ResolveChannel(msg.Channel, claims.UserID)
+	if err != nil || !C_Hub.InRoom(client, channel) {
+		C_Hub.Reply(client, ChatMsg{Type: "error", Ref: msg.Ref, Channel: msg.Channel, Content: "Join the channel first"})
+		return
+	}
+
+	msg, err = CheckCommand(msg, kind)
+	if err != nil {
+		C_Hub.Reply(client, ChatMsg{Type: "error", Ref: msg.Ref, Channel: channel, Content: err.Error()})
+		return
+	}
+
+	system := ChatMsg{Type: "system", Ref: msg.Ref, Channel: channel, Sender: claims.UserID, Role: claims.Role, Action: msg.Type}
+	removed, err := applyCommand(claims, channel, kind, msg, &system)
+	if err != nil {
+		if errors.Is(err, db.ErrMessageNotFound) {
+			C_Hub.Reply(client, ChatMsg{Type: "error", Ref: msg.Ref, Channel: channel, Content: err.Error()})
+			return
+		}
+		log.Printf("❌ Failed to %s in %s: %v", msg.Type, channel, err)
+		C_Hub.Reply(client, ChatMsg{Type: "error", Ref: msg.Ref, Channel: channel, Content: errUnavailable.Error()})
+		return
+	}
+
+	log.Printf("🛡️ %s in %s by %s: %s", system.Action, channel, claims.UserID, system.Content)
+	C_Hub.Publish(system)
+	if removed != "" {
+		C_Hub.PublishMembership(Membership{UserID: removed, Channel: channel, Join: false})
+	}
+}
+
+// CheckCommand returns why msg, sent in a channel of kind, is not a valid
+// moderation command, or the command in its normal form: the open and close
+// "cmd" of the single chat room, kept for old clients, become "mode" commands
+// and modes other than slow drop their seconds.
+func CheckCommand(msg ChatMsg, kind string) (ChatMsg, error) {
+	switch msg.Type {
+	case "cmd", "mode":
+		if msg.Type == "cmd" {
+			msg.Type, msg.Mode = "mode", map[string]string{"open": db.ModeOpen, "close": db.ModeModerators}[msg.Content]
+		}
+		switch msg.Mode {
+		case db.ModeSlow:
+			if msg.Seconds < 1 || msg.Seconds > maxSlowSeconds {
+				return msg, invalidCommand(fmt.Sprintf("slow mode needs seconds between 1 and %d", maxSlowSeconds))
+			}
+		case db.ModeOpen, db.ModeReadOnly, db.ModeModerators:
+			msg.Seconds = 0
+		default:
+			return msg, invalidCommand("mode must be open, read_only, moderators_only or slow")
+		}
+	case "mute", "ban":
+		if msg.Target == "" || msg.Seconds < 0 {
+			return msg, invalidCommand("target is required and seconds may not be negative")
+		}
+		if msg.Type == db.SanctionBan && kind != "line" && kind != "shift" {
+			return msg, invalidCommand("only line and shift channels have bans, mute the user instead")
+		}
+	case "unmute", "unban":
+		if msg.Target == "" {
+			return msg, invalidCommand("target is required")
+		}
+	case "kick":
+		if msg.Target == "" {
+			return msg, invalidCommand("target is required")
+		}
+		if kind != "line" && kind != "shift" {
+			return msg, invalidCommand("only line and shift channels have members to remove")
+		}
+	case "edit", "delete":
+		if _, err := gocql.ParseUUID(msg.ID); err != nil {
+			return msg, invalidCommand("id must be the id of a message")
+		}
+		if msg.Type == "edit" && msg.Content == "" {
+			return msg, invalidCommand("content is required")
+		}
+	default:
+		return msg, invalidCommand("unknown command " + msg.Type)
+	}
+	return msg, nil
+}
+
+// applyCommand stores the change of a command that passed CheckCommand and
+// describes it in system. It returns the user to take out of the channel, if any.
+func applyCommand(claims *auth.Claims, channel, kind string, msg ChatMsg, system *ChatMsg) (string, error) {
+	switch msg.Type {
+	case "mode":
+		system.Mode, system.Seconds = msg.Mode, msg.Seconds
+		system.Content = modeNotice(msg.Mode, msg.Seconds)
+		return "", db.SetChannelMode(channel, kind, msg.Mode, msg.Seconds)
+
+	case "mute", "ban":
+		s := db.Sanction{ChannelID: channel, UserID: msg.Target, Kind: msg.Type, Reason: msg.Content, CreatedBy: claims.UserID}
+		if msg.Seconds > 0 {
+			expires := time.Now().Add(time.Duration(msg.Seconds) * time.Second)
+			s.ExpiresAt = &expires
+		}
+		system.Target, system.Seconds = msg.Target, msg.Seconds
+		system.Content = msg.Target + " was " + pastTense[msg.Type] + until(s.ExpiresAt)
+		if err := db.AddSanction(s); err != nil || msg.Type != db.SanctionBan {
+			return "", err
+		}
+		// A ban also removes the user, who cannot join again until it ends
+		return msg.Target, db.RemoveMember(channel, msg.Target)
+
+	case "unmute", "unban":
+		system.Target = msg.Target
+		system.Content = msg.Target + " was " + pastTense[msg.Type]
+		return "", db.RemoveSanction(channel, msg.Target, msg.Type[len("un"):])
+
+	case "kick":
+		system.Target = msg.Target
+		system.Content = msg.Target + " was removed from the channel"
+		return msg.Target, db.RemoveMember(channel, msg.Target)
+	}
+
+	// edit or delete
+	id, _ := gocql.ParseUUID(msg.ID)
+	system.ID = msg.ID
+	if msg.Type == "delete" {
+		system.Content = "A message was deleted by a moderator"
+		return "", db.DeleteMessage(channel, id)
+	}
+	var err error
+	system.Content = "A message was edited by a moderator"
+	system.Message, err = db.EditMessage(channel, id, msg.Content, claims.UserID)
+	return "", err
+}
+
+func modeNotice(mode string, seconds int) string {
+	switch mode {
+	case db.ModeReadOnly:
+		return "The channel is now read-only"
+	case db.ModeModerators:
+		return "Only moderators can post now"
+	case db.ModeSlow:
+		return fmt.Sprintf("Slow mode is on: one message every %d seconds", seconds)
+	}
+	return "The channel is open"
+}
+
+func until(expires *time.Time) string {
+	if expires == nil {
+		return " until lifted"
+	}
+	return " until " + expires.UTC().Format(time.RFC3339)
+}
@@ -1,10 +1,12 @@
 package routes
 
 import (
-	"Feedback/db"
 	"Feedback/utils"
+	"crypto/subtle"
 	"encoding/json"
 	"net/http"
+	"os"
+	"shared/auth"
 	"sync"
 
 	"github.com/gorilla/websocket"
@@ -21,6 +23,7 @@ type NotifClient struct {
 	Conn   *websocket.Conn
 	Send   chan []byte
 	UserID string
+	Claims *auth.Claims
 }
 
 type NotifHub struct {
@@ -59,25 +62,87 @@ func (h *NotifHub) Run() {
 }
 
 // --- INTERNAL TRIGGER (Called by other microservices) ---
+// The notification goes to TargetID, or to every connected user whose token
+// grants Permission (e.g. camera alerts to "camera:view:dock").
 func TriggerNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	if !internalCaller(r) {
+		http.Error(w, "Forbidden", 403)
+		return
+	}
+
 	type Req struct {
-		TargetID string `json:"target_id"`
-		Content  string `json:"content"`
+		TargetID   string `json:"target_id"`
+		Permission string `json:"permission"`
+		Title      string `json:"title"`
+		Type       string `json:"type"`
+		Content    string `json:"content"`
 	}
 	var req Req
-	json.NewDecoder(r.Body).Decode(&req)
-
-	// Send to specific user
-	N_Hub.Mu.Lock()
-	if client, ok := N_Hub.UserIndex[req.TargetID]; ok {
-		msg, _ := json.Marshal(NotifMsg{Title: "Alert", Content: req.Content, Type: "info"})
-		client.Send <- msg
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Content == "" || (req.TargetID == "" && req.Permission == "") {
+		http.Error(w, "content and target_id or permission are required", 400)
+		return
+	}
+	if req.Title == "" {
+		req.Title = "Alert"
 	}
-	N_Hub.Mu.Unlock()
+	if req.Type == "" {
+		req.Type = "info"
+	}
+	// Users connected to other instances get it through Redis
+	n := Notification{TargetID: req.TargetID, Permission: req.Permission, Msg: NotifMsg{Title: req.Title, Content: req.Content, Type: req.Type}}
+	N_Hub.Deliver(n)
+	publish(notifTopic, peerEvent{Notification: &n})
 
 	w.Write([]byte(`{"status":"sent"}`))
 }
 
+// Notification is a NotifMsg for one user (TargetID) or for every user whose
+// token grants Permission
+type Notification struct {
+	TargetID   string   `json:"target_id,omitempty"`
+	Permission string   `json:"permission,omitempty"`
+	Msg        NotifMsg `json:"msg"`
+}
+
+// Deliver sends n to the matching users connected to this instance
+func (h *NotifHub) Deliver(n Notification) {
+	msg, _ := json.Marshal(n.Msg)
+
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+	if n.TargetID != "" {
+		// Send to specific user
+		if client, ok := h.UserIndex[n.TargetID]; ok {
+			trySend(client, msg)
+		}
+		return
+	}
+	for client := range h.Clients {
+		if client.Claims.Can(n.Permission) {
+			trySend(client, msg)
+		}
+	}
+}
+
+// trySend queues msg for client unless its queue is full
+func trySend(client *NotifClient, msg []byte) {
+	select {
+	case client.Send <- msg:
+	default: // the client is not keeping up, it misses this one
+	}
+}
+
+// internalCaller checks the X-Internal-Token header against INTERNAL_API_TOKEN.
+// Without a configured token every caller is trusted, so keep /internal off
+// public networks.
+func internalCaller(r *http.Request) bool {
+	expected := os.Getenv("INTERNAL_API_TOKEN")
+	if expected == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Internal-Token")), []byte(expected)) == 1
+}
+
 // --- WS HANDLER ---
 var NotifUpgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
@@ -88,22 +153,11 @@ var NotifUpgrader = websocket.Upgrader{
 func NotificationHandler(w http.ResponseWriter, r *http.Request) {
 	token := r.URL.Query().Get("token")
 	claims, err := utils.ParseToken(token)
-	if err != nil { http.Error(w, "Unauthorized", 401); return }
-
-	// 🔒 Validate Login Status
-	userID := claims["user_id"].(string)
-	var isLoggedIn bool
-	if err := db.Session.Query(`SELECT isloggedin FROM auth.users WHERE id = ?`, userID).Scan(&isLoggedIn); err != nil {
-		http.Error(w, "User not found", 401)
-		return
-	}
-	if !isLoggedIn {
-		http.Error(w, "User is not logged in", 403)
-		return
-	}
+	if err != nil { auth.WriteUnauthorized(w, err); return }
 
-	conn, _ := NotifUpgrader.Upgrade(w, r, nil)
-	client := &NotifClient{Conn: conn, Send: make(chan []byte, 256), UserID: claims["user_id"].(string)}
+	conn, err := NotifUpgrader.Upgrade(w, r, nil)
+	if err != nil { return }
+	client := &NotifClient{Conn: conn, Send: make(chan []byte, 256), UserID: claims.UserID, Claims: claims}
 	
 	N_Hub.Register <- client
 
@@ -0,0 +1,44 @@
+package routes
+
+import (
+	"Feedback/db"
+	"Feedback/utils"
+	"log"
+	"net/http"
+)
+
+// ChannelPresence returns the members of a channel the caller belongs to who
+// are connected to chat on any instance; for the global channel, everyone online
+func ChannelPresence(w http.ResponseWriter, r *http.Request) {
+	channel, ok := memberChannel(w, r)
+	if !ok {
+		return
+	}
+	online, err := utils.OnlineUsers()
+	if err != nil {
+		log.Printf("❌ Failed to read presence: %v", err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "Presence is unavailable"})
+		return
+	}
+
+	if channel != globalChannel {
+		members, err := db.ChannelMembers(channel)
+		if err != nil {
+			log.Printf("❌ Failed to list members of %s: %v", channel, err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to list members"})
+			return
+		}
+		isMember := make(map[string]bool, len(members))
+		for _, member := range members {
+			isMember[member] = true
+		}
+		present := online[:0]
+		for _, user := range online {
+			if isMember[user] {
+				present = append(present, user)
+			}
+		}
+		online = present
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"channel": channel, "online": online, "count": len(online)})
+}
@@ -0,0 +1,114 @@
+package test
+
+import (
+	"Feedback/routes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveChannel(t *testing.T) {
+	cases := []struct {
+		id, user     string
+		want, kind   string
+		errSubstring string
+	}{
+		{id: "", user: "u1", want: "global", kind: "global"},
+		{id: "global", user: "u1", want: "global", kind: "global"},
+		{id: "line:press-1", user: "u1", want: "line:press-1", kind: "line"},
+		{id: "shift:night_a", user: "u1", want: "shift:night_a", kind: "shift"},
+		{id: "dm:u2", user: "u1", want: "dm:u1:u2", kind: "direct"},
+		{id: "dm:u1", user: "u2", want: "dm:u1:u2", kind: "direct"},
+		{id: "dm:u2:u1", user: "u1", want: "dm:u1:u2", kind: "direct"},
+		{id: "dm:u2:u3", user: "u1", errSubstring: "not your direct channel"},
+		{id: "dm:u1", user: "u1", errSubstring: "another user"},
+		{id: "dm:", user: "u1", errSubstring: "another user"},
+		{id: "line:Press 1", user: "u1", errSubstring: "line names"},
+		{id: "shift:", user: "u1", errSubstring: "shift names"},
+		{id: "room:x", user: "u1", errSubstring: "channels are"},
+	}
+	for _, tc := range cases {
+		got, kind, err := routes.ResolveChannel(tc.id, tc.user)
+		if tc.errSubstring != "" {
+			require.Error(t, err, tc.id)
+			assert.Contains(t, err.Error(), tc.errSubstring, tc.id)
+			continue
+		}
+		require.NoError(t, err, tc.id)
+		assert.Equal(t, tc.want, got, tc.id)
+		assert.Equal(t, tc.kind, kind, tc.id)
+	}
+}
+
+func newClient(user string) *routes.ChatClient {
+	return &routes.ChatClient{Send: make(chan []byte, 16), UserID: user, Rooms: map[string]bool{"global": true}}
+}
+
+// next returns the next message queued for client, or fails after a second
+func next(t *testing.T, client *routes.ChatClient) routes.ChatMsg {
+	t.Helper()
+	select {
+	case raw := <-client.Send:
+		var msg routes.ChatMsg
+		require.NoError(t, json.Unmarshal(raw, &msg))
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no message for " + client.UserID)
+		return routes.ChatMsg{}
+	}
+}
+
+func assertNothing(t *testing.T, client *routes.ChatClient) {
+	t.Helper()
+	select {
+	case raw := <-client.Send:
+		t.Fatalf("unexpected message for %s: %s", client.UserID, raw)
+	default:
+	}
+}
+
+func TestHubDeliversToChannelMembersOnly(t *testing.T) {
+	hub := routes.NewChatHub()
+	go hub.Run()
+
+	phone, laptop, other := newClient("alice"), newClient("alice"), newClient("bob")
+	for _, c := range []*routes.ChatClient{phone, laptop, other} {
+		hub.Register <- c
+	}
+
+	// Joining applies to every connection of the user
+	hub.Membership <- routes.Membership{UserID: "alice", Channel: "line:press-1", Join: true}
+	for _, c := range []*routes.ChatClient{phone, laptop} {
+		joined := next(t, c)
+		assert.Equal(t, "joined", joined.Type)
+		assert.Equal(t, "line:press-1", joined.Channel)
+	}
+
+	hub.Broadcast <- routes.ChatMsg{Type: "msg", Channel: "line:press-1", Content: "belt stopped"}
+	assert.Equal(t, "belt stopped", next(t, phone).Content)
+	assert.Equal(t, "belt stopped", next(t, laptop).Content)
+
+	hub.Broadcast <- routes.ChatMsg{Type: "msg", Channel: "global", Content: "hello all"}
+	for _, c := range []*routes.ChatClient{phone, laptop, other} {
+		assert.Equal(t, "hello all", next(t, c).Content)
+	}
+	assertNothing(t, other)
+
+	hub.Membership <- routes.Membership{UserID: "alice", Channel: "line:press-1", Join: false}
+	assert.Equal(t, "left", next(t, phone).Type)
+	assert.Equal(t, "left", next(t, laptop).Type)
+	hub.Broadcast <- routes.ChatMsg{Type: "msg", Channel: "line:press-1", Content: "anyone?"}
+	// The next global message arriving first shows the line message went nowhere
+	hub.Broadcast <- routes.ChatMsg{Type: "msg", Channel: "global", Content: "marker"}
+	assert.Equal(t, "marker", next(t, phone).Content)
+	assert.Equal(t, "marker", next(t, laptop).Content)
+	assert.False(t, hub.InRoom(phone, "line:press-1"))
+
+	hub.Unregister <- laptop
+	_, open := <-laptop.Send
+	assert.False(t, open, "unregistering closes the send queue")
+	assert.True(t, hub.Connected("alice"))
+}
@@ -0,0 +1,138 @@
+package test
+
+import (
+	"Feedback/db"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := db.EncodeCursor("2026-03-04", []byte{0x01, 0x02})
+	day, state, err := db.DecodeCursor(cursor)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-03-04", day)
+	assert.Equal(t, []byte{0x01, 0x02}, state)
+
+	// A cursor at the start of a day has no paging state
+	day, state, err = db.DecodeCursor(db.EncodeCursor("2026-03-03", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "2026-03-03", day)
+	assert.Nil(t, state)
+
+	for _, bad := range [][]byte{{}, []byte("2026-03"), []byte("yesterday!"), []byte("2026-13-01\x01")} {
+		_, _, err := db.DecodeCursor(bad)
+		assert.ErrorIs(t, err, db.ErrInvalidCursor, string(bad))
+	}
+}
+
+// fakeHistory is a channel whose days hold messages newest first. Its paging
+// state is the offset of the next message, given only while the day has more.
+type fakeHistory map[string][]db.Message
+
+func (f fakeHistory) pager() db.HistoryPager {
+	return db.HistoryPager{
+		ReadDay: func(day string, pageState []byte, size int) ([]db.Message, []byte, error) {
+			offset := 0
+			if pageState != nil {
+				offset, _ = strconv.Atoi(string(pageState))
+			}
+			messages := f[day][offset:]
+			if len(messages) <= size {
+				return messages, nil, nil
+			}
+			return messages[:size], []byte(strconv.Itoa(offset + size)), nil
+		},
+		PreviousDay: func(day string) (string, error) {
+			previous := ""
+			for d := range f {
+				if d < day && d > previous {
+					previous = d
+				}
+			}
+			return previous, nil
+		},
+	}
+}
+
+func day(date string, n int) []db.Message {
+	messages := make([]db.Message, n)
+	for i := range messages {
+		messages[i].Content = date + "#" + strconv.Itoa(i)
+	}
+	return messages
+}
+
+func contents(messages []db.Message) []string {
+	out := make([]string, len(messages))
+	for i, m := range messages {
+		out[i] = m.Content
+	}
+	return out
+}
+
+func TestPageRollsOverAtExactLimit(t *testing.T) {
+	history := fakeHistory{"2026-03-04": day("2026-03-04", 2), "2026-03-01": day("2026-03-01", 1)}
+	before := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+
+	// The first day ends exactly at the limit: the cursor starts the previous day
+	page, cursor, err := history.pager().Page(before, 2, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2026-03-04#0", "2026-03-04#1"}, contents(page))
+	assert.Equal(t, []byte("2026-03-01"), cursor)
+
+	page, cursor, err = history.pager().Page(before, 2, cursor)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2026-03-01#0"}, contents(page))
+	assert.Nil(t, cursor)
+}
+
+func TestPageWalksEveryMessageOnce(t *testing.T) {
+	history := fakeHistory{
+		"2026-03-04": day("2026-03-04", 3),
+		"2026-03-03": day("2026-03-03", 4),
+		"2026-02-27": day("2026-02-27", 2),
+	}
+	before := time.Date(2026, 3, 4, 23, 0, 0, 0, time.UTC)
+	for limit := 1; limit <= 10; limit++ {
+		var seen []string
+		var cursor []byte
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 20, "limit %d does not end", limit)
+			page, next, err := history.pager().Page(before, limit, cursor)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(page), limit)
+			seen = append(seen, contents(page)...)
+			if next == nil {
+				break
+			}
+			cursor = next
+		}
+		want := append(append(contents(history["2026-03-04"]), contents(history["2026-03-03"])...), contents(history["2026-02-27"])...)
+		assert.Equal(t, want, seen, "limit %d", limit)
+	}
+}
+
+func TestPageRejectsForeignCursor(t *testing.T) {
+	_, _, err := fakeHistory{}.pager().Page(time.Now(), 10, []byte("not a cursor"))
+	assert.ErrorIs(t, err, db.ErrInvalidCursor)
+}
+
+func TestLegacyMessageIDIsStable(t *testing.T) {
+	old, err := gocql.RandomUUID()
+	require.NoError(t, err)
+	created := time.Date(2025, 11, 2, 8, 30, 0, 0, time.UTC)
+
+	id := db.LegacyMessageID(old, created)
+	assert.Equal(t, id, db.LegacyMessageID(old, created), "copying again gives the same id")
+	assert.Equal(t, 1, id.Version())
+	assert.True(t, id.Time().Equal(created))
+
+	other, err := gocql.RandomUUID()
+	require.NoError(t, err)
+	assert.NotEqual(t, id, db.LegacyMessageID(other, created), "messages of the same instant keep apart")
+}
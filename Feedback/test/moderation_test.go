@@ -0,0 +1,100 @@
+package test
+
+import (
+	"Feedback/db"
+	"Feedback/routes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCommand(t *testing.T) {
+	const id = "8b9a6f5e-1a2b-11f0-8de9-0242ac120002"
+	cases := []struct {
+		name    string
+		msg     routes.ChatMsg
+		kind    string
+		want    routes.ChatMsg // checked when errText is empty
+		errText string
+	}{
+		{name: "old open", msg: routes.ChatMsg{Type: "cmd", Content: "open"}, kind: "global",
+			want: routes.ChatMsg{Type: "mode", Content: "open", Mode: db.ModeOpen}},
+		{name: "old close", msg: routes.ChatMsg{Type: "cmd", Content: "close", Seconds: 30}, kind: "global",
+			want: routes.ChatMsg{Type: "mode", Content: "close", Mode: db.ModeModerators}},
+		{name: "old unknown", msg: routes.ChatMsg{Type: "cmd", Content: "lock"}, kind: "global", errText: "mode must be"},
+		{name: "read only drops seconds", msg: routes.ChatMsg{Type: "mode", Mode: db.ModeReadOnly, Seconds: 10}, kind: "line",
+			want: routes.ChatMsg{Type: "mode", Mode: db.ModeReadOnly}},
+		{name: "slow", msg: routes.ChatMsg{Type: "mode", Mode: db.ModeSlow, Seconds: 3600}, kind: "line",
+			want: routes.ChatMsg{Type: "mode", Mode: db.ModeSlow, Seconds: 3600}},
+		{name: "slow without seconds", msg: routes.ChatMsg{Type: "mode", Mode: db.ModeSlow}, kind: "line", errText: "between 1 and 3600"},
+		{name: "slow too slow", msg: routes.ChatMsg{Type: "mode", Mode: db.ModeSlow, Seconds: 3601}, kind: "line", errText: "between 1 and 3600"},
+		{name: "unknown mode", msg: routes.ChatMsg{Type: "mode", Mode: "quiet"}, kind: "line", errText: "mode must be"},
+		{name: "mute in dm", msg: routes.ChatMsg{Type: "mute", Target: "u2", Seconds: 60}, kind: "direct",
+			want: routes.ChatMsg{Type: "mute", Target: "u2", Seconds: 60}},
+		{name: "mute without target", msg: routes.ChatMsg{Type: "mute"}, kind: "line", errText: "target is required"},
+		{name: "negative mute", msg: routes.ChatMsg{Type: "mute", Target: "u2", Seconds: -1}, kind: "line", errText: "may not be negative"},
+		{name: "ban on line", msg: routes.ChatMsg{Type: "ban", Target: "u2"}, kind: "line",
+			want: routes.ChatMsg{Type: "ban", Target: "u2"}},
+		{name: "ban on shift", msg: routes.ChatMsg{Type: "ban", Target: "u2"}, kind: "shift",
+			want: routes.ChatMsg{Type: "ban", Target: "u2"}},
+		{name: "ban on global", msg: routes.ChatMsg{Type: "ban", Target: "u2"}, kind: "global", errText: "only line and shift"},
+		{name: "ban in dm", msg: routes.ChatMsg{Type: "ban", Target: "u2"}, kind: "direct", errText: "only line and shift"},
+		{name: "unban without target", msg: routes.ChatMsg{Type: "unban"}, kind: "line", errText: "target is required"},
+		{name: "kick on global", msg: routes.ChatMsg{Type: "kick", Target: "u2"}, kind: "global", errText: "only line and shift"},
+		{name: "edit", msg: routes.ChatMsg{Type: "edit", ID: id, Content: "fixed"}, kind: "line",
+			want: routes.ChatMsg{Type: "edit", ID: id, Content: "fixed"}},
+		{name: "edit to nothing", msg: routes.ChatMsg{Type: "edit", ID: id}, kind: "line", errText: "content is required"},
+		{name: "delete bad id", msg: routes.ChatMsg{Type: "delete", ID: "42"}, kind: "line", errText: "id must be"},
+		{name: "unknown", msg: routes.ChatMsg{Type: "shout"}, kind: "line", errText: "unknown command shout"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := routes.CheckCommand(tc.msg, tc.kind)
+			if tc.errText != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.errText)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestPostRule(t *testing.T) {
+	expires := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	muted := map[string]db.Sanction{db.SanctionMute: {Kind: db.SanctionMute, ExpiresAt: &expires}}
+	cases := []struct {
+		name      string
+		mode      string
+		moderator bool
+		sanctions map[string]db.Sanction
+		errText   string
+	}{
+		{name: "open", mode: db.ModeOpen},
+		{name: "slow leaves the interval to the caller", mode: db.ModeSlow},
+		{name: "read only", mode: db.ModeReadOnly, errText: "read-only"},
+		{name: "read only for moderators too", mode: db.ModeReadOnly, moderator: true, errText: "read-only"},
+		{name: "moderators only", mode: db.ModeModerators, errText: "Only moderators"},
+		{name: "moderator in moderators only", mode: db.ModeModerators, moderator: true},
+		{name: "muted", mode: db.ModeOpen, sanctions: muted, errText: "muted in this channel until 2026-05-01T10:00:00Z"},
+		{name: "muted moderator", mode: db.ModeOpen, moderator: true, sanctions: muted, errText: "muted"},
+		{name: "muted until lifted", mode: db.ModeOpen,
+			sanctions: map[string]db.Sanction{db.SanctionMute: {Kind: db.SanctionMute}}, errText: "until lifted"},
+		{name: "banned is not muted", mode: db.ModeOpen,
+			sanctions: map[string]db.Sanction{db.SanctionBan: {Kind: db.SanctionBan}}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := routes.PostRule(tc.mode, tc.moderator, tc.sanctions)
+			if tc.errText == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.errText)
+		})
+	}
+}
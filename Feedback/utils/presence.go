@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PresenceTTL is how long a user stays online after the last heartbeat of the
+// instance they are connected to; instances send one every PresenceTTL/3
+const PresenceTTL = 90 * time.Second
+
+// onlineKey is a sorted set of user ids scored by when they go offline
+const onlineKey = "chat:online"
+
+// InstanceID names this Feedback instance in presence entries and fan-out
+// messages, e.g. "feedback-1:3f9c0a7d21e4b6c8"
+var InstanceID = newInstanceID()
+
+func newInstanceID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 8)
+	rand.Read(b)
+	return host + ":" + hex.EncodeToString(b)
+}
+
+// presenceKey holds, per instance a user is connected to, when that entry expires
+func presenceKey(userID string) string { return "chat:presence:" + userID }
+
+// MarkOnline records that this instance has connections of userIDs
+func MarkOnline(userIDs ...string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	deadline := time.Now().Add(PresenceTTL).Unix()
+	pipe := RDB.Pipeline()
+	for _, id := range userIDs {
+		pipe.HSet(Ctx, presenceKey(id), InstanceID, deadline)
+		pipe.Expire(Ctx, presenceKey(id), PresenceTTL)
+		pipe.ZAddGT(Ctx, onlineKey, redis.Z{Score: float64(deadline), Member: id})
+	}
+	pipe.ZRemRangeByScore(Ctx, onlineKey, "-inf", strconv.FormatInt(time.Now().Unix(), 10))
+	_, err := pipe.Exec(Ctx)
+	return err
+}
+
+// MarkOffline records that this instance has no more connections of userID.
+// The user stays online while another instance still has one.
+func MarkOffline(userID string) error {
+	if err := RDB.HDel(Ctx, presenceKey(userID), InstanceID).Err(); err != nil {
+		return err
+	}
+	deadlines, err := RDB.HVals(Ctx, presenceKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for _, d := range deadlines {
+		if deadline, _ := strconv.ParseInt(d, 10, 64); deadline > now {
+			return nil
+		}
+	}
+	// A connection made elsewhere in the meantime comes back with the next heartbeat
+	return RDB.ZRem(Ctx, onlineKey, userID).Err()
+}
+
+// OnlineUsers returns the ids of the users connected to any instance
+func OnlineUsers() ([]string, error) {
+	return RDB.ZRangeByScore(Ctx, onlineKey, &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(time.Now().Unix(), 10),
+		Max: "+inf",
+	}).Result()
+}
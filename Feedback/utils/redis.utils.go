@@ -4,7 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
+	"shared/store"
     "time"
 	"github.com/redis/go-redis/v9"
 )
@@ -15,19 +15,9 @@ var (
 )
 
 func ConnectRedis() {
-	addr := os.Getenv("REDIS_HOST")
-	if addr == "" {
-		addr = "localhost:6379"
-	}
-	password := os.Getenv("REDIS_PASSWORD")
-
-	RDB = redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: password,
-		DB:       0,
-	})
-
-	if err := RDB.Ping(Ctx).Err(); err != nil {
+	var err error
+	RDB, err = store.ConnectRedis(Ctx, store.RedisOptionsFromEnv())
+	if err != nil {
 		log.Fatalf("❌ Failed to connect to Redis: %v", err)
 	}
 
@@ -40,3 +30,18 @@ func SaveUserRole(userID, role string) error {
 func GetUserRole(userID, role string) (string, error) {
 	return RDB.Get(Ctx, "userid:"+userID+":role").Result()
 }
+
+// SlowModeWait takes userID's turn to post in a slow mode channel and returns
+// 0, or how long the user still has to wait when the last turn is too recent
+func SlowModeWait(channel, userID string, interval time.Duration) (time.Duration, error) {
+	key := "chat:slow:" + channel + ":" + userID
+	ok, err := RDB.SetNX(Ctx, key, 1, interval).Result()
+	if err != nil || ok {
+		return 0, err
+	}
+	wait, err := RDB.PTTL(Ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	return max(wait, time.Millisecond), nil
+}
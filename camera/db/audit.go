@@ -0,0 +1,56 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"camera/models"
+
+	"github.com/gocql/gocql"
+)
+
+// CreateAuditTable creates the log of operator actions, partitioned by UTC day
+func CreateAuditTable() {
+	query := `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		day TEXT,
+		id TIMEUUID,
+		actor TEXT,
+		action TEXT,
+		target TEXT,
+		ip TEXT,
+		details MAP<TEXT, TEXT>,
+		PRIMARY KEY (day, id)
+	) WITH CLUSTERING ORDER BY (id DESC);`
+
+	if err := Session.Query(query).Exec(); err != nil {
+		log.Fatal("❌ Error creating audit_log table: ", err)
+	}
+	fmt.Println("✅ Audit log table is ready")
+}
+
+// RecordAudit appends an operator action to the audit log. Failures are
+// logged, not returned, so auditing never blocks the action itself.
+func RecordAudit(actor, action, target, ip string, details map[string]string) {
+	now := time.Now().UTC()
+	err := Session.Query(`INSERT INTO audit_log (day, id, actor, action, target, ip, details) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		DayBucket(now), gocql.UUIDFromTime(now), actor, action, target, ip, details).Exec()
+	if err != nil {
+		log.Printf("❌ Failed to write audit entry %s %s by %s: %v", action, target, actor, err)
+	}
+}
+
+// ListAudit returns the audit entries of one UTC day, newest first
+func ListAudit(day string, limit int) ([]models.AuditEntry, error) {
+	iter := Session.Query(`SELECT id, actor, action, target, ip, details FROM audit_log WHERE day = ? LIMIT ?`, day, limit).Iter()
+
+	entries := []models.AuditEntry{}
+	var e models.AuditEntry
+	for iter.Scan(&e.ID, &e.Actor, &e.Action, &e.Target, &e.IP, &e.Details) {
+		e.At = e.ID.Time()
+		entries = append(entries, e)
+		e = models.AuditEntry{}
+	}
+	return entries, iter.Close()
+}
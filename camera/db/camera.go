@@ -0,0 +1,129 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"camera/models"
+	"shared/store"
+
+	"github.com/gocql/gocql"
+)
+
+const cameraColumns = `id, name, location, source_url, enabled, credentials_ref, recording, retention_days, publish_fps, batch_id, ptz_driver, ptz_url, ptz_profile, created_at, updated_at`
+
+// CreateCameraTable creates the camera registry. When it is empty it is seeded
+// from seed (name -> source URL), so deployments configured through
+// CAMERA_SOURCES keep their cameras.
+func CreateCameraTable(seed map[string]string) {
+	query := `
+	CREATE TABLE IF NOT EXISTS cameras (
+		id UUID PRIMARY KEY,
+		name TEXT,
+		location TEXT,
+		source_url TEXT,
+		enabled BOOLEAN,
+		credentials_ref TEXT,
+		recording TEXT,
+		retention_days INT,
+		publish_fps DOUBLE,
+		batch_id TEXT,
+		ptz_driver TEXT,
+		ptz_url TEXT,
+		ptz_profile TEXT,
+		created_at TIMESTAMP,
+		updated_at TIMESTAMP
+	);`
+
+	if err := Session.Query(query).Exec(); err != nil {
+		log.Fatal("❌ Error creating cameras table: ", err)
+	}
+	// Tables created before recording, frame publishing and PTZ were added
+	added, err := store.EnsureColumns(Session, Keyspace, "cameras", [][2]string{
+		{"recording", "TEXT"},
+		{"retention_days", "INT"},
+		{"publish_fps", "DOUBLE"},
+		{"batch_id", "TEXT"},
+		{"ptz_driver", "TEXT"},
+		{"ptz_url", "TEXT"},
+		{"ptz_profile", "TEXT"},
+	})
+	for _, name := range added {
+		fmt.Printf("✅ Added column cameras.%s\n", name)
+	}
+	if err != nil {
+		log.Fatal("❌ Error migrating cameras table: ", err)
+	}
+
+	cameras, err := ListCameras()
+	if err != nil {
+		log.Fatal("❌ Error reading cameras: ", err)
+	}
+	if len(cameras) == 0 && len(seed) > 0 {
+		for name, sourceURL := range seed {
+			cam := &models.Camera{Name: name, SourceURL: sourceURL, Enabled: true}
+			if err := InsertCamera(cam); err != nil {
+				log.Fatalf("❌ Error seeding camera %s: %v", name, err)
+			}
+		}
+		fmt.Printf("✅ %d cameras seeded from CAMERA_SOURCES\n", len(seed))
+	}
+	fmt.Println("✅ Cameras table is ready")
+}
+
+// ListCameras returns every camera. The registry is small, so it is read whole.
+func ListCameras() ([]models.Camera, error) {
+	iter := Session.Query(`SELECT ` + cameraColumns + ` FROM cameras`).Iter()
+	cameras := []models.Camera{}
+	for {
+		var cam models.Camera
+		if !scanCamera(iter.Scan, &cam) {
+			break
+		}
+		cameras = append(cameras, cam)
+	}
+	return cameras, iter.Close()
+}
+
+// GetCamera loads one camera; gocql.ErrNotFound when there is none
+func GetCamera(id gocql.UUID) (*models.Camera, error) {
+	var cam models.Camera
+	iter := Session.Query(`SELECT `+cameraColumns+` FROM cameras WHERE id = ?`, id).Iter()
+	found := scanCamera(iter.Scan, &cam)
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, gocql.ErrNotFound
+	}
+	return &cam, nil
+}
+
+// InsertCamera stores a new camera, filling in its id and timestamps
+func InsertCamera(cam *models.Camera) error {
+	cam.ID = gocql.TimeUUID()
+	cam.CreatedAt = time.Now()
+	cam.UpdatedAt = cam.CreatedAt
+	return Session.Query(`INSERT INTO cameras (`+cameraColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		cam.ID, cam.Name, cam.Location, cam.SourceURL, cam.Enabled, cam.CredentialsRef, cam.Recording, cam.RetentionDays, cam.PublishFPS, cam.BatchID, cam.PTZDriver, cam.PTZURL, cam.PTZProfile, cam.CreatedAt, cam.UpdatedAt).Exec()
+}
+
+// UpdateCamera overwrites the editable fields of a camera
+func UpdateCamera(cam *models.Camera) error {
+	cam.UpdatedAt = time.Now()
+	return Session.Query(`UPDATE cameras SET name = ?, location = ?, source_url = ?, enabled = ?, credentials_ref = ?, recording = ?, retention_days = ?, publish_fps = ?, batch_id = ?, ptz_driver = ?, ptz_url = ?, ptz_profile = ?, updated_at = ? WHERE id = ?`,
+		cam.Name, cam.Location, cam.SourceURL, cam.Enabled, cam.CredentialsRef, cam.Recording, cam.RetentionDays, cam.PublishFPS, cam.BatchID, cam.PTZDriver, cam.PTZURL, cam.PTZProfile, cam.UpdatedAt, cam.ID).Exec()
+}
+
+// DeleteCamera removes a camera from the registry along with its status
+func DeleteCamera(id gocql.UUID) error {
+	batch := Session.NewBatch(gocql.LoggedBatch)
+	batch.Query(`DELETE FROM cameras WHERE id = ?`, id)
+	batch.Query(`DELETE FROM camera_status WHERE camera_id = ?`, id)
+	return Session.ExecuteBatch(batch)
+}
+
+func scanCamera(scan func(...interface{}) bool, cam *models.Camera) bool {
+	return scan(&cam.ID, &cam.Name, &cam.Location, &cam.SourceURL, &cam.Enabled, &cam.CredentialsRef, &cam.Recording, &cam.RetentionDays, &cam.PublishFPS, &cam.BatchID, &cam.PTZDriver, &cam.PTZURL, &cam.PTZProfile, &cam.CreatedAt, &cam.UpdatedAt)
+}
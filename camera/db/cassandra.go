@@ -3,27 +3,28 @@ package db
 import (
 	"fmt"
 	"log"
-	"os"
+	"shared/store"
 
 	"github.com/gocql/gocql"
 )
 
 var Session *gocql.Session
 
-func ConnectCassandra() {
-	host := os.Getenv("CASSANDRA_HOST")
-	keyspace := os.Getenv("CASSANDRA_KEYSPACE")
+// Keyspace is the keyspace the session is bound to
+var Keyspace string
 
-	cluster := gocql.NewCluster(host)
-	cluster.Keyspace = keyspace
-	cluster.Consistency = gocql.Quorum
+func ConnectCassandra() {
+	// The camera keyspace is created on first start
+	opts := store.CassandraOptionsFromEnv("camera")
+	opts.CreateKeyspace = true
 
 	var err error
-	Session, err = cluster.CreateSession()
+	Session, err = store.ConnectCassandra(opts)
 	if err != nil {
 		log.Fatal("❌ Failed to connect to Cassandra:", err)
 	}
-	fmt.Println("✅ Connected to Cassandra")
+	Keyspace = opts.Keyspace
+	fmt.Printf("✅ Connected to Cassandra keyspace '%s'\n", opts.Keyspace)
 }
 
 func Close() {
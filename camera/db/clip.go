@@ -0,0 +1,136 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"camera/models"
+
+	"github.com/gocql/gocql"
+)
+
+const clipColumns = `clip_id, camera_id, started_at, ended_at, object_key, size, frames, trigger`
+
+// CreateClipTables creates the clip index. Clips are partitioned by camera and
+// UTC day (the bucket), and clip_buckets lists the buckets of every camera so
+// retention can find old ones without scanning clips.
+func CreateClipTables() {
+	queries := []string{`
+	CREATE TABLE IF NOT EXISTS clips (
+		camera_id UUID,
+		bucket TEXT,
+		started_at TIMESTAMP,
+		clip_id TIMEUUID,
+		ended_at TIMESTAMP,
+		object_key TEXT,
+		size BIGINT,
+		frames INT,
+		trigger TEXT,
+		PRIMARY KEY ((camera_id, bucket), started_at, clip_id)
+	);`, `
+	CREATE TABLE IF NOT EXISTS clip_buckets (
+		camera_id UUID,
+		bucket TEXT,
+		PRIMARY KEY (camera_id, bucket)
+	);`}
+
+	for _, query := range queries {
+		if err := Session.Query(query).Exec(); err != nil {
+			log.Fatal("❌ Error creating clip tables: ", err)
+		}
+	}
+	fmt.Println("✅ Clip tables are ready")
+}
+
+// DayBucket returns the UTC day partition of t, e.g. "2026-03-04"
+func DayBucket(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// InsertClip indexes a stored clip, filling in its id
+func InsertClip(clip *models.Clip) error {
+	clip.ID = gocql.UUIDFromTime(clip.StartedAt)
+	bucket := DayBucket(clip.StartedAt)
+	batch := Session.NewBatch(gocql.LoggedBatch)
+	batch.Query(`INSERT INTO clips (bucket, `+clipColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		bucket, clip.ID, clip.CameraID, clip.StartedAt, clip.EndedAt, clip.ObjectKey, clip.Size, clip.Frames, clip.Trigger)
+	batch.Query(`INSERT INTO clip_buckets (camera_id, bucket) VALUES (?, ?)`, clip.CameraID, bucket)
+	return Session.ExecuteBatch(batch)
+}
+
+// ListClips returns the clips of a camera started in [from, to), oldest first
+func ListClips(cameraID gocql.UUID, from, to time.Time) ([]models.Clip, error) {
+	clips := []models.Clip{}
+	for day := from.UTC().Truncate(24 * time.Hour); day.Before(to); day = day.Add(24 * time.Hour) {
+		iter := Session.Query(`SELECT `+clipColumns+` FROM clips WHERE camera_id = ? AND bucket = ? AND started_at >= ? AND started_at < ?`,
+			cameraID, DayBucket(day), from, to).Iter()
+		for {
+			var clip models.Clip
+			if !scanClip(iter.Scan, &clip) {
+				break
+			}
+			clips = append(clips, clip)
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return clips, nil
+}
+
+// GetClip loads one clip of a camera; gocql.ErrNotFound when there is none
+func GetClip(cameraID, clipID gocql.UUID) (*models.Clip, error) {
+	startedAt := clipID.Time()
+	var clip models.Clip
+	iter := Session.Query(`SELECT `+clipColumns+` FROM clips WHERE camera_id = ? AND bucket = ? AND started_at = ? AND clip_id = ?`,
+		cameraID, DayBucket(startedAt), startedAt, clipID).Iter()
+	found := scanClip(iter.Scan, &clip)
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, gocql.ErrNotFound
+	}
+	return &clip, nil
+}
+
+// DayBuckets returns every camera id -> day buckets holding clips
+func DayBuckets() (map[gocql.UUID][]string, error) {
+	iter := Session.Query(`SELECT camera_id, bucket FROM clip_buckets`).Iter()
+	buckets := make(map[gocql.UUID][]string)
+	var (
+		cameraID gocql.UUID
+		bucket   string
+	)
+	for iter.Scan(&cameraID, &bucket) {
+		buckets[cameraID] = append(buckets[cameraID], bucket)
+	}
+	return buckets, iter.Close()
+}
+
+// ListBucketClips returns every clip of one day bucket of a camera
+func ListBucketClips(cameraID gocql.UUID, bucket string) ([]models.Clip, error) {
+	iter := Session.Query(`SELECT `+clipColumns+` FROM clips WHERE camera_id = ? AND bucket = ?`, cameraID, bucket).Iter()
+	clips := []models.Clip{}
+	for {
+		var clip models.Clip
+		if !scanClip(iter.Scan, &clip) {
+			break
+		}
+		clips = append(clips, clip)
+	}
+	return clips, iter.Close()
+}
+
+// DeleteDayBucket drops a day bucket of a camera from the index
+func DeleteDayBucket(cameraID gocql.UUID, bucket string) error {
+	batch := Session.NewBatch(gocql.LoggedBatch)
+	batch.Query(`DELETE FROM clips WHERE camera_id = ? AND bucket = ?`, cameraID, bucket)
+	batch.Query(`DELETE FROM clip_buckets WHERE camera_id = ? AND bucket = ?`, cameraID, bucket)
+	return Session.ExecuteBatch(batch)
+}
+
+func scanClip(scan func(...interface{}) bool, clip *models.Clip) bool {
+	return scan(&clip.ID, &clip.CameraID, &clip.StartedAt, &clip.EndedAt, &clip.ObjectKey, &clip.Size, &clip.Frames, &clip.Trigger)
+}
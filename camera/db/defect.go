@@ -0,0 +1,125 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"camera/models"
+
+	"github.com/gocql/gocql"
+)
+
+const defectColumns = `defect_id, line, camera_id, batch_id, class, confidence, bbox, sequence, captured_at, inspected_at, model_version`
+
+// CreateDefectTables creates the inspection results tables. Defects are
+// partitioned by production line and UTC day for time range queries, and
+// copied by batch for batch reports.
+func CreateDefectTables() {
+	queries := []string{`
+	CREATE TABLE IF NOT EXISTS defects_by_line (
+		line TEXT,
+		day TEXT,
+		captured_at TIMESTAMP,
+		defect_id TEXT,
+		camera_id TEXT,
+		batch_id TEXT,
+		class TEXT,
+		confidence DOUBLE,
+		bbox LIST<DOUBLE>,
+		sequence BIGINT,
+		inspected_at TIMESTAMP,
+		model_version TEXT,
+		PRIMARY KEY ((line, day), captured_at, defect_id)
+	);`, `
+	CREATE TABLE IF NOT EXISTS defects_by_batch (
+		batch_id TEXT,
+		captured_at TIMESTAMP,
+		defect_id TEXT,
+		line TEXT,
+		camera_id TEXT,
+		class TEXT,
+		confidence DOUBLE,
+		bbox LIST<DOUBLE>,
+		sequence BIGINT,
+		inspected_at TIMESTAMP,
+		model_version TEXT,
+		PRIMARY KEY (batch_id, captured_at, defect_id)
+	);`}
+
+	for _, query := range queries {
+		if err := Session.Query(query).Exec(); err != nil {
+			log.Fatal("❌ Error creating defect tables: ", err)
+		}
+	}
+	fmt.Println("✅ Defect tables are ready")
+}
+
+// InsertDefects stores the defects of one inspected frame. Writing the same
+// defects again overwrites them.
+func InsertDefects(defects []models.Defect) error {
+	if len(defects) == 0 {
+		return nil
+	}
+	batch := Session.NewBatch(gocql.LoggedBatch)
+	for _, d := range defects {
+		batch.Query(`INSERT INTO defects_by_line (day, `+defectColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			DayBucket(d.CapturedAt), d.ID, d.Line, d.CameraID, d.BatchID, d.Class, d.Confidence, d.BBox, d.Sequence, d.CapturedAt, d.InspectedAt, d.ModelVersion)
+		if d.BatchID != "" {
+			batch.Query(`INSERT INTO defects_by_batch (`+defectColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				d.ID, d.Line, d.CameraID, d.BatchID, d.Class, d.Confidence, d.BBox, d.Sequence, d.CapturedAt, d.InspectedAt, d.ModelVersion)
+		}
+	}
+	return Session.ExecuteBatch(batch)
+}
+
+// ListLineDefects returns the defects of a line captured in [from, to) that
+// match filter, oldest first, stopping after limit. truncated reports whether
+// more would match.
+func ListLineDefects(line string, from, to time.Time, filter models.DefectFilter, limit int) ([]models.Defect, bool, error) {
+	defects := []models.Defect{}
+	for day := from.UTC().Truncate(24 * time.Hour); day.Before(to) && len(defects) <= limit; day = day.Add(24 * time.Hour) {
+		iter := Session.Query(`SELECT `+defectColumns+` FROM defects_by_line WHERE line = ? AND day = ? AND captured_at >= ? AND captured_at < ?`,
+			line, DayBucket(day), from, to).Iter()
+		var err error
+		if defects, err = scanDefects(iter, defects, filter, limit); err != nil {
+			return nil, false, err
+		}
+	}
+	return trimDefects(defects, limit)
+}
+
+// ListBatchDefects returns the defects of a batch captured in [from, to) that
+// match filter, oldest first, stopping after limit. truncated reports whether
+// more would match.
+func ListBatchDefects(batchID string, from, to time.Time, filter models.DefectFilter, limit int) ([]models.Defect, bool, error) {
+	iter := Session.Query(`SELECT `+defectColumns+` FROM defects_by_batch WHERE batch_id = ? AND captured_at >= ? AND captured_at < ?`,
+		batchID, from, to).Iter()
+	defects, err := scanDefects(iter, []models.Defect{}, filter, limit)
+	if err != nil {
+		return nil, false, err
+	}
+	return trimDefects(defects, limit)
+}
+
+// scanDefects appends the rows of iter that match filter to defects, paging
+// through the partition until one more than limit match
+func scanDefects(iter *gocql.Iter, defects []models.Defect, filter models.DefectFilter, limit int) ([]models.Defect, error) {
+	for len(defects) <= limit {
+		var d models.Defect
+		if !iter.Scan(&d.ID, &d.Line, &d.CameraID, &d.BatchID, &d.Class, &d.Confidence, &d.BBox, &d.Sequence, &d.CapturedAt, &d.InspectedAt, &d.ModelVersion) {
+			break
+		}
+		if filter.Matches(d) {
+			defects = append(defects, d)
+		}
+	}
+	return defects, iter.Close()
+}
+
+func trimDefects(defects []models.Defect, limit int) ([]models.Defect, bool, error) {
+	if len(defects) > limit {
+		return defects[:limit], true, nil
+	}
+	return defects, false, nil
+}
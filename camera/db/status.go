@@ -0,0 +1,53 @@
+package db
+
+import (
+	"fmt"
+	"log"
+
+	"camera/models"
+
+	"github.com/gocql/gocql"
+)
+
+const statusColumns = `camera_id, name, status, latency_ms, last_seen, checked_at, changed_at, failures, error`
+
+// CreateCameraStatusTable creates the table the health prober writes to
+func CreateCameraStatusTable() {
+	query := `
+	CREATE TABLE IF NOT EXISTS camera_status (
+		camera_id UUID PRIMARY KEY,
+		name TEXT,
+		status TEXT,
+		latency_ms INT,
+		last_seen TIMESTAMP,
+		checked_at TIMESTAMP,
+		changed_at TIMESTAMP,
+		failures INT,
+		error TEXT
+	);`
+
+	if err := Session.Query(query).Exec(); err != nil {
+		log.Fatal("❌ Error creating camera_status table: ", err)
+	}
+	fmt.Println("✅ Camera status table is ready")
+}
+
+// ListCameraStatus returns the latest status of every probed camera
+func ListCameraStatus() (map[gocql.UUID]models.CameraStatus, error) {
+	iter := Session.Query(`SELECT ` + statusColumns + ` FROM camera_status`).Iter()
+	statuses := make(map[gocql.UUID]models.CameraStatus)
+	for {
+		var s models.CameraStatus
+		if !iter.Scan(&s.CameraID, &s.Name, &s.Status, &s.LatencyMS, &s.LastSeen, &s.CheckedAt, &s.ChangedAt, &s.Failures, &s.Error) {
+			break
+		}
+		statuses[s.CameraID] = s
+	}
+	return statuses, iter.Close()
+}
+
+// SaveCameraStatus records the outcome of a health check
+func SaveCameraStatus(s *models.CameraStatus) error {
+	return Session.Query(`INSERT INTO camera_status (`+statusColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.CameraID, s.Name, s.Status, s.LatencyMS, s.LastSeen, s.CheckedAt, s.ChangedAt, s.Failures, s.Error).Exec()
+}
@@ -0,0 +1,90 @@
+package frames
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Frame is one sampled camera frame for the inspection service
+type Frame struct {
+	CameraID   string
+	Camera     string
+	Location   string
+	BatchID    string
+	Sequence   int64
+	CapturedAt time.Time
+	JPEG       []byte
+}
+
+// Message encodes a frame for Kafka. The value is the JPEG itself and the
+// metadata travels in headers; the key is the camera id, so the frames of a
+// camera stay in order on one partition.
+func (f Frame) Message() kafka.Message {
+	return kafka.Message{
+		Key:   []byte(f.CameraID),
+		Value: f.JPEG,
+		Time:  f.CapturedAt,
+		Headers: []kafka.Header{
+			{Key: "content_type", Value: []byte("image/jpeg")},
+			{Key: "camera_id", Value: []byte(f.CameraID)},
+			{Key: "camera", Value: []byte(f.Camera)},
+			{Key: "location", Value: []byte(f.Location)},
+			{Key: "batch_id", Value: []byte(f.BatchID)},
+			{Key: "sequence", Value: []byte(strconv.FormatInt(f.Sequence, 10))},
+			{Key: "captured_at", Value: []byte(f.CapturedAt.UTC().Format(time.RFC3339Nano))},
+		},
+	}
+}
+
+// Publisher sends frames to the inspection service
+type Publisher interface {
+	Publish(ctx context.Context, frame Frame) error
+}
+
+// KafkaPublisher publishes frames to a Kafka topic
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher reads KAFKA_BROKERS (comma separated, default
+// localhost:9092) and FRAME_TOPIC (default "camera_frames"). Brokers are only
+// contacted on the first publish.
+func NewKafkaPublisher() *KafkaPublisher {
+	var brokers []string
+	for _, broker := range strings.Split(os.Getenv("KAFKA_BROKERS"), ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			brokers = append(brokers, broker)
+		}
+	}
+	if len(brokers) == 0 {
+		brokers = []string{"localhost:9092"}
+	}
+	topic := os.Getenv("FRAME_TOPIC")
+	if topic == "" {
+		topic = "camera_frames"
+	}
+
+	return &KafkaPublisher{writer: &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Topic:                  topic,
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireOne,
+		BatchTimeout:           10 * time.Millisecond,
+		WriteTimeout:           10 * time.Second,
+		AllowAutoTopicCreation: true,
+	}}
+}
+
+func (p *KafkaPublisher) Publish(ctx context.Context, frame Frame) error {
+	return p.writer.WriteMessages(ctx, frame.Message())
+}
+
+// Close flushes pending frames and closes the broker connections
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,131 @@
+package frames
+
+import (
+	"camera/stream"
+	"context"
+	"log"
+	"time"
+)
+
+// publishTimeout bounds publishing one frame; a frame that cannot be
+// delivered in time is dropped
+const publishTimeout = 5 * time.Second
+
+// Config says how one camera is sampled
+type Config struct {
+	Camera   string // channel name
+	CameraID string
+	Location string
+	BatchID  string
+	// FPS is how many frames per second are published, e.g. 0.5 for one every two seconds
+	FPS float64
+}
+
+// Sampler publishes frames of one channel at its configured rate
+type Sampler struct {
+	cfg       Config
+	publisher Publisher
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// StartSampler samples ch until Stop is called or the channel is closed
+func StartSampler(ch *stream.Channel, cfg Config, publisher Publisher) *Sampler {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Sampler{cfg: cfg, publisher: publisher, cancel: cancel, done: make(chan struct{})}
+	go s.run(ctx, ch)
+	return s
+}
+
+// Stop ends sampling and waits for the frame being published
+func (s *Sampler) Stop() {
+	s.cancel()
+	<-s.done
+}
+
+func (s *Sampler) run(ctx context.Context, ch *stream.Channel) {
+	defer close(s.done)
+	frames, leave := ch.Subscribe()
+	defer leave()
+
+	interval := time.Duration(float64(time.Second) / s.cfg.FPS)
+	var (
+		last     time.Time
+		sequence int64
+		failing  bool
+	)
+	for {
+		var jpeg []byte
+		select {
+		case <-ctx.Done():
+			return
+		case f, ok := <-frames:
+			if !ok {
+				return // the channel was removed
+			}
+			jpeg = f
+		}
+		now := time.Now()
+		if now.Sub(last) < interval {
+			continue
+		}
+		last = now
+		sequence++
+
+		frame := Frame{
+			CameraID:   s.cfg.CameraID,
+			Camera:     s.cfg.Camera,
+			Location:   s.cfg.Location,
+			BatchID:    s.cfg.BatchID,
+			Sequence:   sequence,
+			CapturedAt: now,
+			JPEG:       jpeg,
+		}
+		publishCtx, cancel := context.WithTimeout(ctx, publishTimeout)
+		err := s.publisher.Publish(publishCtx, frame)
+		cancel()
+		// Logged when publishing starts and stops failing, not for every frame
+		if err != nil && !failing && ctx.Err() == nil {
+			log.Printf("⚠️ Frames of camera %s are not published: %v", s.cfg.Camera, err)
+		} else if err == nil && failing {
+			log.Printf("✅ Frames of camera %s are published again", s.cfg.Camera)
+		}
+		failing = err != nil
+	}
+}
+
+// Manager runs the samplers of the cameras this instance publishes
+type Manager struct {
+	samplers *stream.Workers[Config]
+}
+
+// NewManager returns a manager publishing through publisher
+func NewManager(publisher Publisher) *Manager {
+	return &Manager{samplers: stream.NewWorkers(func(ch *stream.Channel, cfg Config) stream.Worker {
+		return StartSampler(ch, cfg, publisher)
+	})}
+}
+
+// Sync samples exactly the cameras in configs with a positive FPS, taking
+// their channels from hub. Samplers whose settings or channel changed are
+// restarted.
+func (m *Manager) Sync(hub *stream.Hub, configs []Config) {
+	wanted := make(map[string]Config, len(configs))
+	for _, cfg := range configs {
+		if cfg.FPS > 0 {
+			wanted[cfg.Camera] = cfg
+		}
+	}
+	m.samplers.Sync(hub, wanted)
+}
+
+// Sampling tells whether this instance publishes the camera called name
+func (m *Manager) Sampling(name string) bool {
+	return m.samplers.Running(name)
+}
+
+// Close stops every sampler
+func (m *Manager) Close() {
+	m.samplers.Close()
+}
@@ -0,0 +1,57 @@
+package health
+
+import (
+	"camera/models"
+	"time"
+)
+
+// Transition is a status change worth alerting about
+type Transition int
+
+const (
+	NoChange Transition = iota
+	WentOffline
+	Recovered
+)
+
+// Record applies the outcome of one check to the previous status of a camera.
+// A camera goes offline after offlineAfter consecutive failures, so a single
+// dropped frame does not raise an alert. checkErr nil means a frame arrived
+// after latency.
+func Record(prev models.CameraStatus, latency time.Duration, checkErr error, now time.Time, offlineAfter int) (models.CameraStatus, Transition) {
+	next := prev
+	if next.Status == "" {
+		next.Status = models.StatusUnknown
+	}
+	next.CheckedAt = now
+
+	status := next.Status
+	if checkErr == nil {
+		next.Failures = 0
+		next.LatencyMS = int(latency.Milliseconds())
+		next.LastSeen = now
+		next.Error = ""
+		status = models.StatusOnline
+	} else {
+		next.Failures++
+		next.Error = checkErr.Error()
+		if next.Failures >= offlineAfter {
+			status = models.StatusOffline
+		}
+	}
+
+	if status == next.Status {
+		return next, NoChange
+	}
+	previous := next.Status
+	next.Status = status
+	next.ChangedAt = now
+	switch {
+	case status == models.StatusOffline:
+		return next, WentOffline
+	case previous == models.StatusOffline:
+		return next, Recovered
+	default: // first successful check
+		return next, NoChange
+	}
+}
@@ -0,0 +1,95 @@
+package inspection
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	minRetry = time.Second
+	maxRetry = time.Minute
+)
+
+// ConsumerOptionsFromEnv reads KAFKA_BROKERS (comma separated, default
+// localhost:9092) and INSPECTION_TOPIC (default "inspection_results"). Every
+// instance joins the "camera-inspection" consumer group, so each result is
+// stored once.
+func ConsumerOptionsFromEnv() kafka.ReaderConfig {
+	var brokers []string
+	for _, broker := range strings.Split(os.Getenv("KAFKA_BROKERS"), ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			brokers = append(brokers, broker)
+		}
+	}
+	if len(brokers) == 0 {
+		brokers = []string{"localhost:9092"}
+	}
+	topic := os.Getenv("INSPECTION_TOPIC")
+	if topic == "" {
+		topic = "inspection_results"
+	}
+	return kafka.ReaderConfig{
+		Brokers:  brokers,
+		Topic:    topic,
+		GroupID:  "camera-inspection",
+		MinBytes: 1,
+		MaxBytes: 10 << 20,
+		MaxWait:  time.Second,
+	}
+}
+
+// Consume reads inspection results until ctx is done, calling store for each
+// valid one. A message is committed once stored; when store fails it is
+// retried with backoff, so results are not lost while Cassandra is down.
+// Malformed messages are logged and skipped.
+func Consume(ctx context.Context, config kafka.ReaderConfig, store func(Result) error) error {
+	reader := kafka.NewReader(config)
+	defer reader.Close()
+
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		var result Result
+		if err := json.Unmarshal(msg.Value, &result); err != nil {
+			log.Printf("⚠️ Skipping inspection result at offset %d: %v", msg.Offset, err)
+		} else if err := result.Validate(); err != nil {
+			log.Printf("⚠️ Skipping inspection result at offset %d: %v", msg.Offset, err)
+		} else if !storeWithRetry(ctx, result, store) {
+			return nil
+		}
+
+		if err := reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			log.Printf("⚠️ Failed to commit inspection result at offset %d: %v", msg.Offset, err)
+		}
+	}
+}
+
+// storeWithRetry keeps calling store until it succeeds; false when ctx ends first
+func storeWithRetry(ctx context.Context, result Result, store func(Result) error) bool {
+	backoff := minRetry
+	for {
+		err := store(result)
+		if err == nil {
+			return true
+		}
+		log.Printf("❌ Failed to store inspection result of %s: %v — retrying in %s", result.CameraID, err, backoff)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRetry)
+	}
+}
@@ -0,0 +1,62 @@
+package inspection
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Result is what the defect detector publishes for one inspected frame
+type Result struct {
+	// ResultID identifies the result, so a redelivered message overwrites
+	// itself; without one it is derived from the camera, time and sequence
+	ResultID string `json:"result_id"`
+	CameraID string `json:"camera_id"`
+	// Line is the production line; it defaults to the camera's location
+	Line         string    `json:"line"`
+	BatchID      string    `json:"batch_id"`
+	Sequence     int64     `json:"sequence"`
+	CapturedAt   time.Time `json:"captured_at"`
+	InspectedAt  time.Time `json:"inspected_at"`
+	ModelVersion string    `json:"model_version"`
+	Defects      []Defect  `json:"defects"`
+}
+
+// Defect is one detection in a frame
+type Defect struct {
+	Class      string  `json:"class"`
+	Confidence float64 `json:"confidence"`
+	// BBox is x, y, width and height, relative to the frame (0 to 1)
+	BBox []float64 `json:"bbox"`
+}
+
+// Validate rejects results that cannot be stored or queried
+func (r *Result) Validate() error {
+	if r.CameraID == "" && r.Line == "" {
+		return errors.New("camera_id or line is required")
+	}
+	if r.CapturedAt.IsZero() {
+		return errors.New("captured_at is required")
+	}
+	for i, d := range r.Defects {
+		if d.Class == "" {
+			return fmt.Errorf("defect %d has no class", i)
+		}
+		if d.Confidence < 0 || d.Confidence > 1 {
+			return fmt.Errorf("defect %d confidence must be between 0 and 1", i)
+		}
+		if len(d.BBox) != 0 && len(d.BBox) != 4 {
+			return fmt.Errorf("defect %d bbox must be [x, y, width, height]", i)
+		}
+	}
+	return nil
+}
+
+// DefectID identifies defect i of the result
+func (r *Result) DefectID(i int) string {
+	id := r.ResultID
+	if id == "" {
+		id = fmt.Sprintf("%s/%d/%d", r.CameraID, r.CapturedAt.UnixMilli(), r.Sequence)
+	}
+	return fmt.Sprintf("%s#%d", id, i)
+}
@@ -0,0 +1,104 @@
+package inspection
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultShifts is used when SHIFTS is not set
+const DefaultShifts = "morning=06:00-14:00,evening=14:00-22:00,night=22:00-06:00"
+
+// Shift is a named daily time window. A shift ending at or before its start
+// runs past midnight.
+type Shift struct {
+	Name  string
+	Start time.Duration // since midnight
+	End   time.Duration
+}
+
+// Shifts covers the day; times are read in Location
+type Shifts struct {
+	List     []Shift
+	Location *time.Location
+}
+
+// ParseShifts reads "name=HH:MM-HH:MM,..." in loc
+func ParseShifts(spec string, loc *time.Location) (Shifts, error) {
+	shifts := Shifts{Location: loc}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, window, ok := strings.Cut(entry, "=")
+		start, end, ok2 := strings.Cut(window, "-")
+		if !ok || !ok2 || name == "" {
+			return Shifts{}, fmt.Errorf("invalid shift %q, want name=HH:MM-HH:MM", entry)
+		}
+		from, err := clock(start)
+		if err != nil {
+			return Shifts{}, fmt.Errorf("shift %s: %w", name, err)
+		}
+		to, err := clock(end)
+		if err != nil {
+			return Shifts{}, fmt.Errorf("shift %s: %w", name, err)
+		}
+		shifts.List = append(shifts.List, Shift{Name: strings.TrimSpace(name), Start: from, End: to})
+	}
+	if len(shifts.List) == 0 {
+		return Shifts{}, fmt.Errorf("no shifts in %q", spec)
+	}
+	return shifts, nil
+}
+
+// ShiftsFromEnv reads SHIFTS (default DefaultShifts) in the SHIFT_TIMEZONE
+// time zone (an IANA name, default the server's)
+func ShiftsFromEnv() (Shifts, error) {
+	loc := time.Local
+	if tz := os.Getenv("SHIFT_TIMEZONE"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return Shifts{}, err
+		}
+	}
+	spec := os.Getenv("SHIFTS")
+	if spec == "" {
+		spec = DefaultShifts
+	}
+	return ParseShifts(spec, loc)
+}
+
+// At returns the shift t falls in and when that shift started. Times outside
+// every shift belong to an unnamed "other" shift starting at midnight.
+func (s Shifts) At(t time.Time) (string, time.Time) {
+	t = t.In(s.Location)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.Location)
+	sinceMidnight := t.Sub(midnight)
+	for _, shift := range s.List {
+		if shift.Start < shift.End {
+			if sinceMidnight >= shift.Start && sinceMidnight < shift.End {
+				return shift.Name, midnight.Add(shift.Start)
+			}
+			continue
+		}
+		// Past midnight: the evening part, or the morning part of yesterday's shift
+		if sinceMidnight >= shift.Start {
+			return shift.Name, midnight.Add(shift.Start)
+		}
+		if sinceMidnight < shift.End {
+			yesterday := time.Date(t.Year(), t.Month(), t.Day()-1, 0, 0, 0, 0, s.Location)
+			return shift.Name, yesterday.Add(shift.Start)
+		}
+	}
+	return "other", midnight
+}
+
+func clock(hhmm string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(hhmm))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", hhmm)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -2,11 +2,20 @@ package main
 
 import (
 	"camera/db"
+	"camera/inspection"
 	"camera/middleware"
+	"camera/ptz"
 	"camera/routes"
+	"camera/stream"
 	"camera/utils"
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"shared/auth"
+	"shared/ratelimit"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis_rate/v10"
@@ -19,32 +28,93 @@ func main() {
 		log.Fatal("Error loading .env file")
 	}
 	log.Println("env loaded successfully")
-    //----------redis setup----------------
+	//----------redis setup----------------
 	utils.ConnectRedis()
-    //----------cassandra setup------------
+	//----------token verification---------
+	utils.InitVerifier()
+	//----------cassandra setup------------
 	db.ConnectCassandra()
-    //----------router setup---------------
+	seed, err := stream.SourcesFromEnv()
+	if err != nil {
+		log.Fatal("❌ Invalid camera sources: ", err)
+	}
+	db.CreateCameraTable(seed)
+	db.CreateCameraStatusTable()
+	db.CreateClipTables()
+	db.CreateDefectTables()
+	db.CreateAuditTable()
+	//----------camera streams-------------
+	routes.Streams, _ = stream.NewHub(nil)
+	if err := routes.InitRecording(); err != nil {
+		log.Fatal("❌ Invalid clip store: ", err)
+	}
+	routes.InitFramePublishing()
+	if fake, _ := strconv.ParseBool(os.Getenv("PTZ_FAKE_DRIVER")); fake {
+		ptz.RegisterFake()
+	}
+	if err := routes.ReloadCameras(); err != nil {
+		log.Printf("⚠️ Some cameras could not be loaded: %v", err)
+	}
+	routes.StartCameraReloader(30 * time.Second)
+	routes.StartThumbnailer(routes.ThumbnailInterval())
+	routes.StartProber(routes.ProbeInterval())
+	routes.StartRetention(routes.RetentionInterval())
+	//----------inspection results---------
+	routes.Shifts, err = inspection.ShiftsFromEnv()
+	if err != nil {
+		log.Fatal("❌ Invalid shifts: ", err)
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	routes.StartInspectionConsumer(ctx)
+	defer routes.Streams.Close()
+	defer routes.Recorders.Close()
+	defer routes.FramePublisher.Close()
+	defer routes.Samplers.Close()
+	log.Printf("✅ %d camera channels configured", len(routes.Streams.Names()))
+	//----------router setup---------------
 	router := gin.Default()
-	router.Use(middleware.NewRedisUserRateLimiter(utils.RDB, redis_rate.PerMinute(10)))
-	router.GET("/ping", func(c *gin.Context) {
+	// HLS players poll the playlist and fetch a segment every couple of seconds,
+	// so these routes are not rate limited
+	hls := router.Group("/api/v0/cctv/stream")
+	hls.Use(middleware.CameraAccess(), middleware.ChannelAccess())
+	hls.GET("/:channel/hls/:file", routes.StreamHLS)
+	// Operators send a PTZ command every few hundred milliseconds while
+	// steering; the per-camera control lock keeps it to one operator, and a
+	// limit of its own keeps a stuck client from flooding the camera
+	steer := router.Group("/api/v0/cctv/cameras")
+	steer.Use(middleware.CameraAccess(), ratelimit.PerUserIn(utils.RDB, "ptz", redis_rate.PerSecond(5)))
+	steer.POST("/:id/ptz", routes.PTZ)
+
+	// Runs after CameraAccess, so it counts the verified user
+	rateLimit := ratelimit.PerUser(utils.RDB, redis_rate.PerMinute(10))
+	router.GET("/ping", rateLimit, func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "pong",
 			"status":  http.StatusOK,
 		})
 	})
-	router.Group("api/v0/cctv")
-	router.Use(middleware.CameraAccess())
-	router.GET("/stream/channel1",
-		routes.CameraChannel1,
-	)
-	router.GET("/stream/channel2",
-		routes.CameraChannel2,
-	)
-	router.GET("/stream/channel3",
-		routes.CameraChannel3,
-	)
-	router.GET("/stream/channel4",
-		routes.CameraChannel4,
-	)
+	cctv := router.Group("/api/v0/cctv")
+	cctv.Use(middleware.CameraAccess(), rateLimit)
+	cctv.GET("/channels", routes.ListChannels)
+	cctv.GET("/status", routes.CameraStatus)
+	cctv.GET("/stream/:channel", middleware.ChannelAccess(), routes.StreamMJPEG)
+	cctv.GET("/cameras/grid", routes.Grid)
+	cctv.GET("/cameras/:id/snapshot", routes.Snapshot)
+	cctv.GET("/cameras/:id/clips", routes.ListClips)
+	cctv.GET("/cameras/:id/clips/:clip", routes.DownloadClip)
+	cctv.GET("/cameras/:id/ptz/presets", routes.PTZPresets)
+	cctv.GET("/audit", auth.RequirePermission("audit:read"), routes.ListAuditLog)
+
+	canInspect := auth.RequirePermission("inspection:read")
+	cctv.GET("/inspection/defects", canInspect, routes.ListDefects)
+	cctv.GET("/inspection/summary", canInspect, routes.DefectSummary)
+
+	canManage := auth.RequirePermission("cameras:manage")
+	cctv.GET("/cameras", canManage, routes.ListCameras)
+	cctv.POST("/cameras", canManage, routes.CreateCamera)
+	cctv.GET("/cameras/:id", canManage, routes.GetCamera)
+	cctv.PATCH("/cameras/:id", canManage, routes.UpdateCamera)
+	cctv.DELETE("/cameras/:id", canManage, routes.DeleteCamera)
 	router.Run(":3000") // listens on 0.0.0.0:8080 by default
-}
\ No newline at end of file
+}
@@ -1,68 +1,26 @@
 package middleware
 
 import (
-	"camera/db"
 	"camera/utils"
-	"net/http"
-	"strings"
+	"shared/auth"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gocql/gocql"
 )
 
+// CameraAccess authenticates the caller and stores the token's claims for
+// auth.RequirePermission. Logged out and revoked tokens are rejected by the
+// verifier's Redis lookup.
 func CameraAccess() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing token"})
-			c.Abort()
-			return
-		}
-
-		token := strings.TrimPrefix(authHeader, "Bearer ")
-		claims, err := utils.ParseToken(token)
+		claims, err := utils.ParseToken(auth.BearerToken(c.Request))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
-			return
-		}
-
-		role, ok := claims["role"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
-			return
-		}
-
-		email, ok := claims["email"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
-			return
-		}
-
-		var isLoggedIn bool
-		var userRole string
-		if err := db.Session.Query(`SELECT role, isloggedin FROM users WHERE email = ? LIMIT 1`, email).Consistency(gocql.One).Scan(&userRole, &isLoggedIn); err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User validation failed"})
-			c.Abort()
-			return
-		}
-
-		if !isLoggedIn {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session ended"})
-			c.Abort()
-			return
-		}
-
-		if userRole != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
-			c.Abort()
+			auth.Unauthorized(c, err)
 			return
 		}
 
-		c.Set("user_id", claims["user_id"])
-		c.Set("role", role)
+		c.Set(auth.ContextKey, claims)
+		c.Set("user_id", claims.UserID)
+		c.Set("role", claims.Role)
 		c.Next()
 	}
 }
\ No newline at end of file
@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"shared/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChannelAccess requires camera:view:<channel> for the :channel of the path.
+// It must run after CameraAccess.
+func ChannelAccess() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth.RequirePermission("camera:view:" + c.Param("channel"))(c)
+	}
+}
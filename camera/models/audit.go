@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// AuditEntry is one operator action, such as a PTZ move
+type AuditEntry struct {
+	ID      gocql.UUID        `json:"id"`
+	Actor   string            `json:"actor"`
+	Action  string            `json:"action"`
+	Target  string            `json:"target"`
+	IP      string            `json:"ip"`
+	Details map[string]string `json:"details"`
+	At      time.Time         `json:"at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Camera is one CCTV camera. Its name is the channel viewers stream from and
+// the last segment of its camera:view:<name> permission.
+type Camera struct {
+	ID        gocql.UUID `json:"id"`
+	Name      string     `json:"name"`
+	Location  string     `json:"location"`
+	SourceURL string     `json:"source_url"`
+	Enabled   bool       `json:"enabled"`
+	// CredentialsRef names the CAMERA_CREDENTIALS_<REF> environment variable
+	// holding "user:password" for the source; credentials are never stored here
+	CredentialsRef string `json:"credentials_ref"`
+	// Recording is RecordingOff, RecordingMotion or RecordingContinuous
+	Recording string `json:"recording"`
+	// RetentionDays is how long clips are kept; 0 uses RECORDING_RETENTION_DAYS
+	RetentionDays int `json:"retention_days"`
+	// PublishFPS is how many frames per second go to the inspection service; 0 is off
+	PublishFPS float64 `json:"publish_fps"`
+	// BatchID is the production batch in front of the camera, sent with its frames
+	BatchID string `json:"batch_id"`
+	// PTZDriver names the ptz driver of a PTZ camera ("onvif"); empty for fixed cameras
+	PTZDriver string `json:"ptz_driver"`
+	// PTZURL is the camera's PTZ service, authenticated with CredentialsRef
+	PTZURL     string    `json:"ptz_url"`
+	PTZProfile string    `json:"ptz_profile"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Recording modes of a camera
+const (
+	RecordingOff        = "off"
+	RecordingMotion     = "motion"
+	RecordingContinuous = "continuous"
+)
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Clip is a recorded segment of a camera. Its id is a time UUID of StartedAt,
+// so the id alone locates it in the index.
+type Clip struct {
+	ID        gocql.UUID `json:"id"`
+	CameraID  gocql.UUID `json:"camera_id"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   time.Time  `json:"ended_at"`
+	// ObjectKey is where the clip is kept in the clip store
+	ObjectKey string `json:"-"`
+	Size      int64  `json:"size"`
+	Frames    int    `json:"frames"`
+	// Trigger is the recording mode that produced the clip
+	Trigger string `json:"trigger"`
+}
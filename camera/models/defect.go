@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Defect is one detection reported by the inspection service
+type Defect struct {
+	ID           string    `json:"id"`
+	Line         string    `json:"line"`
+	CameraID     string    `json:"camera_id,omitempty"`
+	BatchID      string    `json:"batch_id,omitempty"`
+	Class        string    `json:"class"`
+	Confidence   float64   `json:"confidence"`
+	BBox         []float64 `json:"bbox,omitempty"`
+	Sequence     int64     `json:"sequence"`
+	CapturedAt   time.Time `json:"captured_at"`
+	InspectedAt  time.Time `json:"inspected_at"`
+	ModelVersion string    `json:"model_version,omitempty"`
+}
+
+// DefectFilter selects defects; empty fields match any value
+type DefectFilter struct {
+	Line          string
+	CameraID      string
+	Class         string
+	MinConfidence float64
+}
+
+// Matches tells whether d passes every field of f
+func (f DefectFilter) Matches(d Defect) bool {
+	return (f.Line == "" || d.Line == f.Line) &&
+		(f.CameraID == "" || d.CameraID == f.CameraID) &&
+		(f.Class == "" || d.Class == f.Class) &&
+		d.Confidence >= f.MinConfidence
+}
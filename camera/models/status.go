@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Camera statuses recorded by the health prober
+const (
+	StatusUnknown = "unknown"
+	StatusOnline  = "online"
+	StatusOffline = "offline"
+)
+
+// CameraStatus is the latest health check of a camera
+type CameraStatus struct {
+	CameraID gocql.UUID `json:"camera_id"`
+	Name     string     `json:"name"`
+	Status   string     `json:"status"`
+	// LatencyMS is the time to a fresh frame in the last successful check
+	LatencyMS int       `json:"latency_ms"`
+	LastSeen  time.Time `json:"last_seen"`
+	CheckedAt time.Time `json:"checked_at"`
+	ChangedAt time.Time `json:"changed_at"`
+	// Failures counts consecutive failed checks
+	Failures int    `json:"failures"`
+	Error    string `json:"error,omitempty"`
+}
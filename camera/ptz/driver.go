@@ -0,0 +1,86 @@
+package ptz
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Velocity is a pan, tilt and zoom speed, each between -1 and 1. Positive pan
+// turns right, positive tilt up and positive zoom in.
+type Velocity struct {
+	Pan  float64 `json:"pan"`
+	Tilt float64 `json:"tilt"`
+	Zoom float64 `json:"zoom"`
+}
+
+// Preset is a position saved on the camera
+type Preset struct {
+	Token string `json:"token"`
+	Name  string `json:"name"`
+}
+
+// Driver controls one PTZ camera. The operations follow the ONVIF PTZ service,
+// which most vendors implement; other protocols plug in through Register.
+type Driver interface {
+	// ContinuousMove moves at v until Stop or for at most timeout
+	ContinuousMove(ctx context.Context, v Velocity, timeout time.Duration) error
+	Stop(ctx context.Context) error
+	GotoPreset(ctx context.Context, token string) error
+	// SetPreset saves the current position and returns its token
+	SetPreset(ctx context.Context, name string) (string, error)
+	Presets(ctx context.Context) ([]Preset, error)
+}
+
+// Config locates a camera's PTZ service
+type Config struct {
+	URL      string
+	Username string
+	Password string
+	// Profile is the media profile to control, e.g. the ONVIF ProfileToken
+	Profile string
+}
+
+// Factory opens a driver for one camera
+type Factory func(Config) (Driver, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Factory)
+)
+
+// Register makes a driver available by name, like database/sql drivers. It
+// panics when name is registered twice.
+func Register(name string, factory Factory) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if _, dup := drivers[name]; dup {
+		panic("ptz: driver registered twice: " + name)
+	}
+	drivers[name] = factory
+}
+
+// Open returns a driver of the named kind for cfg
+func Open(name string, cfg Config) (Driver, error) {
+	driversMu.RLock()
+	factory, ok := drivers[name]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown PTZ driver %q", name)
+	}
+	return factory(cfg)
+}
+
+// Drivers returns the registered driver names in order
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
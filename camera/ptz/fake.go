@@ -0,0 +1,109 @@
+package ptz
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxFakeCommands is how many of the latest commands a Fake remembers
+const maxFakeCommands = 100
+
+var (
+	fakeOnce sync.Once
+	fakesMu  sync.Mutex
+	fakes    = make(map[string]*Fake)
+)
+
+// RegisterFake makes the "fake" driver available. It is not registered by
+// default, main does it when PTZ_FAKE_DRIVER is set for development.
+func RegisterFake() {
+	fakeOnce.Do(func() {
+		Register("fake", func(cfg Config) (Driver, error) { return FakeCamera(cfg.URL), nil })
+	})
+}
+
+// Fake is an in-memory PTZ camera for tests and development. Cameras using the
+// "fake" driver with the same URL share one Fake.
+type Fake struct {
+	mu       sync.Mutex
+	commands []string
+	presets  []Preset
+	// Err, when set, fails every command
+	Err error
+}
+
+// FakeCamera returns the fake camera at url
+func FakeCamera(url string) *Fake {
+	fakesMu.Lock()
+	defer fakesMu.Unlock()
+	fake, ok := fakes[url]
+	if !ok {
+		fake = &Fake{}
+		fakes[url] = fake
+	}
+	return fake
+}
+
+// Commands returns the latest commands received, oldest first, e.g.
+// "move 0.5 0 0 500ms"
+func (f *Fake) Commands() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.commands...)
+}
+
+func (f *Fake) record(command string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	if len(f.commands) == maxFakeCommands {
+		f.commands = append(f.commands[:0], f.commands[1:]...)
+	}
+	f.commands = append(f.commands, command)
+	return nil
+}
+
+func (f *Fake) ContinuousMove(ctx context.Context, v Velocity, timeout time.Duration) error {
+	return f.record(fmt.Sprintf("move %g %g %g %s", v.Pan, v.Tilt, v.Zoom, timeout))
+}
+
+func (f *Fake) Stop(ctx context.Context) error {
+	return f.record("stop")
+}
+
+func (f *Fake) GotoPreset(ctx context.Context, token string) error {
+	f.mu.Lock()
+	known := false
+	for _, p := range f.presets {
+		known = known || p.Token == token
+	}
+	f.mu.Unlock()
+	if !known {
+		return fmt.Errorf("no preset %q", token)
+	}
+	return f.record("goto " + token)
+}
+
+func (f *Fake) SetPreset(ctx context.Context, name string) (string, error) {
+	if err := f.record("set " + name); err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	token := fmt.Sprintf("preset-%d", len(f.presets)+1)
+	f.presets = append(f.presets, Preset{Token: token, Name: name})
+	return token, nil
+}
+
+func (f *Fake) Presets(ctx context.Context) ([]Preset, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	return append([]Preset{}, f.presets...), nil
+}
@@ -0,0 +1,36 @@
+package recording
+
+import "camera/stream"
+
+// Manager runs the recorders of the cameras this instance records
+type Manager struct {
+	recorders *stream.Workers[Config]
+}
+
+// NewManager returns a manager storing clips in store; saved is called for
+// every stored clip
+func NewManager(store Store, saved func(Clip)) *Manager {
+	return &Manager{recorders: stream.NewWorkers(func(ch *stream.Channel, cfg Config) stream.Worker {
+		return Start(ch, cfg, store, saved)
+	})}
+}
+
+// Sync records exactly the cameras in configs, taking their channels from hub.
+// Recorders whose settings or channel changed are restarted.
+func (m *Manager) Sync(hub *stream.Hub, configs []Config) {
+	wanted := make(map[string]Config, len(configs))
+	for _, cfg := range configs {
+		wanted[cfg.Camera] = cfg
+	}
+	m.recorders.Sync(hub, wanted)
+}
+
+// Recording tells whether this instance records the camera called name
+func (m *Manager) Recording(name string) bool {
+	return m.recorders.Running(name)
+}
+
+// Close stops every recorder and waits for their last clips to be stored
+func (m *Manager) Close() {
+	m.recorders.Close()
+}
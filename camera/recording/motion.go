@@ -0,0 +1,67 @@
+package recording
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+)
+
+const (
+	// Frames are compared on a motionWidth x motionHeight grid of luma samples
+	motionWidth  = 64
+	motionHeight = 48
+	// pixelDelta is how much a sample's luma must change to count as moved,
+	// which keeps sensor noise and compression artifacts out
+	pixelDelta = 24
+)
+
+// MotionDetector reports motion between consecutive frames of one camera
+type MotionDetector struct {
+	// Threshold is the fraction of samples that must change, e.g. 0.02
+	Threshold float64
+	prev      []uint8
+}
+
+// Detect compares frame with the previous one. The first frame never moves.
+func (d *MotionDetector) Detect(frame []byte) (bool, error) {
+	img, err := jpeg.Decode(bytes.NewReader(frame))
+	if err != nil {
+		return false, err
+	}
+	samples := lumaGrid(img)
+	prev := d.prev
+	d.prev = samples
+	if len(prev) != len(samples) {
+		return false, nil
+	}
+
+	changed := 0
+	for i := range samples {
+		delta := int(samples[i]) - int(prev[i])
+		if delta > pixelDelta || delta < -pixelDelta {
+			changed++
+		}
+	}
+	return float64(changed)/float64(len(samples)) > d.Threshold, nil
+}
+
+// lumaGrid samples the luma of img at the centre of every grid cell
+func lumaGrid(img image.Image) []uint8 {
+	bounds := img.Bounds()
+	samples := make([]uint8, 0, motionWidth*motionHeight)
+	ycbcr, isYCbCr := img.(*image.YCbCr)
+	for y := 0; y < motionHeight; y++ {
+		sy := bounds.Min.Y + (2*y+1)*bounds.Dy()/(2*motionHeight)
+		for x := 0; x < motionWidth; x++ {
+			sx := bounds.Min.X + (2*x+1)*bounds.Dx()/(2*motionWidth)
+			if isYCbCr {
+				// Camera JPEGs decode to YCbCr, whose Y plane is the luma already
+				samples = append(samples, ycbcr.Y[ycbcr.YOffset(sx, sy)])
+				continue
+			}
+			samples = append(samples, color.GrayModel.Convert(img.At(sx, sy)).(color.Gray).Y)
+		}
+	}
+	return samples
+}
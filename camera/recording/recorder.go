@@ -0,0 +1,264 @@
+package recording
+
+import (
+	"camera/models"
+	"camera/stream"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxSegment caps the length of a clip, whatever the configuration says
+	MaxSegment = 10 * time.Minute
+	// uploadTimeout bounds storing one clip
+	uploadTimeout = 5 * time.Minute
+)
+
+// Config says how one camera is recorded
+type Config struct {
+	Camera   string // channel name
+	CameraID string // stored with the clips and used in their keys
+	Mode     string // models.RecordingMotion or models.RecordingContinuous
+	// FPS is how many frames per second are kept; the rest are dropped
+	FPS int
+	// Segment is the length of a continuous clip and the longest motion clip
+	Segment time.Duration
+	// PreRoll is kept before motion starts, PostRoll after it stops
+	PreRoll  time.Duration
+	PostRoll time.Duration
+	// Threshold is the MotionDetector threshold
+	Threshold float64
+}
+
+// ConfigFromEnv returns the recording settings shared by every camera:
+// RECORDING_FPS (default 5), RECORDING_SEGMENT_SECONDS (60),
+// RECORDING_PREROLL_SECONDS (5), RECORDING_POSTROLL_SECONDS (10) and
+// MOTION_THRESHOLD (0.02)
+func ConfigFromEnv() Config {
+	cfg := Config{
+		FPS:       envInt("RECORDING_FPS", 5),
+		Segment:   time.Duration(envInt("RECORDING_SEGMENT_SECONDS", 60)) * time.Second,
+		PreRoll:   time.Duration(envInt("RECORDING_PREROLL_SECONDS", 5)) * time.Second,
+		PostRoll:  time.Duration(envInt("RECORDING_POSTROLL_SECONDS", 10)) * time.Second,
+		Threshold: 0.02,
+	}
+	if threshold, err := strconv.ParseFloat(os.Getenv("MOTION_THRESHOLD"), 64); err == nil && threshold > 0 && threshold < 1 {
+		cfg.Threshold = threshold
+	}
+	return cfg
+}
+
+// Clip describes a stored recording
+type Clip struct {
+	CameraID string
+	Camera   string
+	Key      string
+	Start    time.Time
+	End      time.Time
+	Frames   int
+	Size     int64
+	Trigger  string
+}
+
+// Recorder records one camera channel into clips. Clips are raw MJPEG, a
+// concatenation of the JPEG frames, played with e.g. `ffplay -f mjpeg`.
+type Recorder struct {
+	cfg   Config
+	store Store
+	saved func(Clip)
+
+	cancel  context.CancelFunc
+	done    chan struct{}
+	uploads sync.WaitGroup
+}
+
+// Start records ch until Stop is called or the channel is closed. saved is
+// called for every clip once it is in store.
+func Start(ch *stream.Channel, cfg Config, store Store, saved func(Clip)) *Recorder {
+	cfg.FPS = max(cfg.FPS, 1)
+	if cfg.Segment <= 0 || cfg.Segment > MaxSegment {
+		cfg.Segment = MaxSegment
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Recorder{cfg: cfg, store: store, saved: saved, cancel: cancel, done: make(chan struct{})}
+	go r.run(ctx, ch)
+	return r
+}
+
+// Stop ends the recording and waits for the last clips to be stored
+func (r *Recorder) Stop() {
+	r.cancel()
+	<-r.done
+	r.uploads.Wait()
+}
+
+type timedFrame struct {
+	at    time.Time
+	frame []byte
+}
+
+func (r *Recorder) run(ctx context.Context, ch *stream.Channel) {
+	defer close(r.done)
+	frames, leave := ch.Subscribe()
+	defer leave()
+
+	interval := time.Second / time.Duration(r.cfg.FPS)
+	detector := &MotionDetector{Threshold: r.cfg.Threshold}
+	var (
+		seg        *segment
+		last       time.Time
+		lastMotion time.Time
+		preRoll    []timedFrame
+	)
+	defer func() {
+		if seg != nil {
+			r.finish(seg)
+		}
+	}()
+
+	for {
+		var frame []byte
+		select {
+		case <-ctx.Done():
+			return
+		case f, ok := <-frames:
+			if !ok {
+				return // the channel was removed
+			}
+			frame = f
+		}
+		now := time.Now()
+		if now.Sub(last) < interval {
+			continue
+		}
+		last = now
+
+		if r.cfg.Mode == models.RecordingContinuous {
+			if seg == nil {
+				seg = r.open(now)
+			}
+			seg.write(now, frame)
+			if now.Sub(seg.start) >= r.cfg.Segment {
+				r.finish(seg)
+				seg = nil
+			}
+			continue
+		}
+
+		moving, err := detector.Detect(frame)
+		if err != nil {
+			continue
+		}
+		if moving {
+			lastMotion = now
+		}
+		if seg == nil {
+			if !moving {
+				preRoll = append(preRoll, timedFrame{now, frame})
+				for len(preRoll) > 0 && now.Sub(preRoll[0].at) > r.cfg.PreRoll {
+					preRoll = preRoll[1:]
+				}
+				continue
+			}
+			start := now
+			if len(preRoll) > 0 {
+				start = preRoll[0].at
+			}
+			seg = r.open(start)
+			for _, f := range preRoll {
+				seg.write(f.at, f.frame)
+			}
+			preRoll = nil
+		}
+		seg.write(now, frame)
+		if now.Sub(lastMotion) >= r.cfg.PostRoll || now.Sub(seg.start) >= r.cfg.Segment {
+			r.finish(seg)
+			seg = nil
+		}
+	}
+}
+
+// segment is a clip being written to a temporary file
+type segment struct {
+	file   *os.File
+	start  time.Time
+	end    time.Time
+	frames int
+	size   int64
+	err    error
+}
+
+func (r *Recorder) open(start time.Time) *segment {
+	// Clip ids carry the start time at the millisecond precision Cassandra keeps
+	seg := &segment{start: start.Truncate(time.Millisecond)}
+	seg.file, seg.err = os.CreateTemp("", "clip-*.mjpeg")
+	if seg.err != nil {
+		log.Printf("❌ Recording of camera %s failed: %v", r.cfg.Camera, seg.err)
+	}
+	return seg
+}
+
+func (s *segment) write(at time.Time, frame []byte) {
+	if s.err != nil {
+		return
+	}
+	n, err := s.file.Write(frame)
+	s.size += int64(n)
+	s.err = err
+	s.end = at
+	s.frames++
+}
+
+// finish stores the segment in the background, so frames keep being recorded meanwhile
+func (r *Recorder) finish(seg *segment) {
+	if seg.file == nil {
+		return
+	}
+	r.uploads.Add(1)
+	go func() {
+		defer r.uploads.Done()
+		defer os.Remove(seg.file.Name())
+		defer seg.file.Close()
+		if seg.err != nil {
+			log.Printf("❌ Clip of camera %s dropped: %v", r.cfg.Camera, seg.err)
+			return
+		}
+		if seg.frames == 0 {
+			return
+		}
+		if _, err := seg.file.Seek(0, 0); err != nil {
+			log.Printf("❌ Clip of camera %s dropped: %v", r.cfg.Camera, err)
+			return
+		}
+
+		clip := Clip{
+			CameraID: r.cfg.CameraID,
+			Camera:   r.cfg.Camera,
+			Key:      fmt.Sprintf("%s/%s/%d.mjpeg", r.cfg.CameraID, seg.start.UTC().Format(time.DateOnly), seg.start.UnixMilli()),
+			Start:    seg.start,
+			End:      seg.end,
+			Frames:   seg.frames,
+			Size:     seg.size,
+			Trigger:  r.cfg.Mode,
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+		defer cancel()
+		if err := r.store.Put(ctx, clip.Key, seg.file, seg.size); err != nil {
+			log.Printf("❌ Failed to store clip of camera %s: %v", r.cfg.Camera, err)
+			return
+		}
+		r.saved(clip)
+	}()
+}
+
+func envInt(key string, fallback int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n > 0 {
+		return n
+	}
+	return fallback
+}
@@ -0,0 +1,122 @@
+package recording
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotFound is returned when a clip object does not exist
+var ErrNotFound = errors.New("clip not found")
+
+// Store keeps the recorded clips. Keys are slash separated paths such as
+// "<camera id>/<day>/<start>.mjpeg".
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Open returns the clip and its size; ErrNotFound when it does not exist
+	Open(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	// Delete removes a clip; deleting a missing clip is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// StoreFromEnv picks the clip store from CLIP_STORE: "local" (the default)
+// keeps clips under CLIP_DIR (default "recordings"), "s3" in an S3 compatible
+// bucket configured by S3_ENDPOINT, S3_BUCKET, S3_REGION, S3_ACCESS_KEY and
+// S3_SECRET_KEY.
+func StoreFromEnv() (Store, error) {
+	switch kind := os.Getenv("CLIP_STORE"); kind {
+	case "", "local":
+		dir := os.Getenv("CLIP_DIR")
+		if dir == "" {
+			dir = "recordings"
+		}
+		return &LocalStore{Dir: dir}, nil
+	case "s3":
+		s := &S3Store{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    os.Getenv("S3_REGION"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		}
+		if s.Endpoint == "" || s.Bucket == "" || s.AccessKey == "" || s.SecretKey == "" {
+			return nil, errors.New("CLIP_STORE=s3 needs S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY")
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown CLIP_STORE %q, want local or s3", kind)
+	}
+}
+
+// LocalStore keeps clips as files under Dir. It also stands in for object
+// storage in development.
+type LocalStore struct {
+	Dir string
+}
+
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	// Written next to its final name and renamed, so a clip is never seen half written
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalStore) Open(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, 0, ErrNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	// Drop the day directory once its last clip is gone; this fails while it has others
+	os.Remove(filepath.Dir(path))
+	return nil
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", fmt.Errorf("invalid clip key %q", key)
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
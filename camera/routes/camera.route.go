@@ -1,31 +1,107 @@
 package routes
 
 import (
+	"camera/stream"
+	"errors"
+	"fmt"
 	"net/http"
+	"path/filepath"
+	"regexp"
+	"shared/auth"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func CameraChannel1(c*gin.Context) {
-   c.JSON(http.StatusOK, gin.H{
-    "message": "Camera channel 1",
-})
-}
+// Streams holds the configured cameras, set up in main
+var Streams *stream.Hub
+
+// hlsFile matches the playlist and segment names written by the transcoder
+var hlsFile = regexp.MustCompile(`^(index\.m3u8|segment\d+\.ts)$`)
 
-func CameraChannel2(c*gin.Context) {
-   c.JSON(http.StatusOK, gin.H{
-    "message": "Camera channel 2",
-})
+// ListChannels returns the camera channels the caller may view
+func ListChannels(c *gin.Context) {
+	claims := auth.ClaimsFrom(c)
+	channels := []string{}
+	for _, name := range Streams.Names() {
+		if claims.Can("camera:view:" + name) {
+			channels = append(channels, name)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"channels": channels})
 }
 
-func CameraChannel3(c*gin.Context) {
-   c.JSON(http.StatusOK, gin.H{
-    "message": "Camera channel 3",
-})
+// StreamMJPEG streams a channel as multipart/x-mixed-replace JPEG frames, which
+// browsers play in a plain <img> tag. All viewers of a channel share one
+// connection to the camera.
+func StreamMJPEG(c *gin.Context) {
+	ch, ok := Streams.Channel(c.Param("channel"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown channel"})
+		return
+	}
+
+	frames, leave := ch.Subscribe()
+	defer leave()
+
+	c.Header("Content-Type", "multipart/x-mixed-replace; boundary=frame")
+	c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case frame, ok := <-frames:
+			if !ok {
+				return
+			}
+			if _, err := fmt.Fprintf(c.Writer, "--frame\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n", len(frame)); err != nil {
+				return
+			}
+			// The frame is shared with the other viewers, it is only read
+			if _, err := c.Writer.Write(frame); err != nil {
+				return
+			}
+			if _, err := c.Writer.WriteString("\r\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
 }
 
-func CameraChannel4(c*gin.Context) {
-   c.JSON(http.StatusOK, gin.H{
-    "message": "Camera channel 4",
-})
+// StreamHLS serves the HLS playlist (index.m3u8) and segments of a channel.
+// The transcoder runs while players keep fetching.
+func StreamHLS(c *gin.Context) {
+	ch, ok := Streams.Channel(c.Param("channel"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown channel"})
+		return
+	}
+	file := c.Param("file")
+	if !hlsFile.MatchString(file) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown file"})
+		return
+	}
+
+	dir, err := ch.HLS(10 * time.Second)
+	if errors.Is(err, stream.ErrHLSNotReady) {
+		c.Header("Retry-After", "2")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Stream is starting, retry shortly"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start stream"})
+		return
+	}
+
+	if file == stream.HLSPlaylist {
+		c.Header("Content-Type", "application/vnd.apple.mpegurl")
+		c.Header("Cache-Control", "no-cache")
+	} else {
+		c.Header("Content-Type", "video/mp2t")
+	}
+	c.File(filepath.Join(dir, file))
 }
@@ -0,0 +1,303 @@
+package routes
+
+import (
+	"camera/db"
+	"camera/models"
+	"camera/ptz"
+	"camera/stream"
+	"camera/utils"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocql/gocql"
+)
+
+var (
+	// cameraName keeps names usable as a URL segment and a permission segment
+	cameraName     = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+	credentialsRef = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+)
+
+type CameraRequest struct {
+	Name           *string  `json:"name"`
+	Location       *string  `json:"location"`
+	SourceURL      *string  `json:"source_url"`
+	Enabled        *bool    `json:"enabled"`
+	CredentialsRef *string  `json:"credentials_ref"`
+	Recording      *string  `json:"recording"`
+	RetentionDays  *int     `json:"retention_days"`
+	PublishFPS     *float64 `json:"publish_fps"`
+	BatchID        *string  `json:"batch_id"`
+	PTZDriver      *string  `json:"ptz_driver"`
+	PTZURL         *string  `json:"ptz_url"`
+	PTZProfile     *string  `json:"ptz_profile"`
+}
+
+// ListCameras returns the whole camera registry
+func ListCameras(c *gin.Context) {
+	cameras, err := db.ListCameras()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list cameras"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"cameras": cameras})
+}
+
+func GetCamera(c *gin.Context) {
+	cam, ok := loadCamera(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"camera": cam})
+}
+
+// CreateCamera registers a camera; it can be streamed right away
+func CreateCamera(c *gin.Context) {
+	var req CameraRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == nil || req.SourceURL == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name and source_url are required"})
+		return
+	}
+
+	cam := &models.Camera{Enabled: true}
+	if !applyCameraRequest(c, cam, req) {
+		return
+	}
+	if err := db.InsertCamera(cam); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create camera"})
+		return
+	}
+	log.Printf("📷 Camera %s (%s) created by %s", cam.Name, cam.ID, c.GetString("user_id"))
+	reloadAfterChange()
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Camera created", "camera": cam})
+}
+
+// UpdateCamera changes the given fields of a camera. Viewers of a camera whose
+// name or source changes are disconnected.
+func UpdateCamera(c *gin.Context) {
+	var req CameraRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cam, ok := loadCamera(c)
+	if !ok {
+		return
+	}
+	if !applyCameraRequest(c, cam, req) {
+		return
+	}
+	if err := db.UpdateCamera(cam); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update camera"})
+		return
+	}
+	log.Printf("📷 Camera %s (%s) updated by %s", cam.Name, cam.ID, c.GetString("user_id"))
+	reloadAfterChange()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Camera updated", "camera": cam})
+}
+
+// DeleteCamera removes a camera and disconnects its viewers
+func DeleteCamera(c *gin.Context) {
+	cam, ok := loadCamera(c)
+	if !ok {
+		return
+	}
+	if err := db.DeleteCamera(cam.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete camera"})
+		return
+	}
+	log.Printf("📷 Camera %s (%s) deleted by %s", cam.Name, cam.ID, c.GetString("user_id"))
+	reloadAfterChange()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Camera deleted"})
+}
+
+// ReloadCameras points Streams at the enabled cameras of the registry and
+// starts or stops their recording and frame publishing
+func ReloadCameras() error {
+	cameras, err := db.ListCameras()
+	if err != nil {
+		return err
+	}
+	sources := make(map[string]string)
+	for _, cam := range cameras {
+		if !cam.Enabled {
+			continue
+		}
+		sourceURL, err := utils.SourceURL(cam)
+		if err != nil {
+			log.Printf("⚠️ Camera %s skipped: %v", cam.Name, err)
+			continue
+		}
+		sources[cam.Name] = sourceURL
+	}
+	err = Streams.Sync(sources)
+	syncRecorders(cameras)
+	syncSamplers(cameras)
+	return err
+}
+
+// StartCameraReloader re-reads the registry every interval, so changes made
+// through another instance are picked up
+func StartCameraReloader(interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			if err := ReloadCameras(); err != nil {
+				log.Printf("❌ Failed to reload cameras: %v", err)
+			}
+		}
+	}()
+}
+
+func reloadAfterChange() {
+	if err := ReloadCameras(); err != nil {
+		log.Printf("❌ Failed to reload cameras: %v", err)
+	}
+}
+
+// holdsLease tells whether this instance holds the lease to work on a camera,
+// e.g. to "record" it, so each camera is worked on by one instance. Without
+// Redis nobody can take over, so an instance keeps the cameras it works on.
+func holdsLease(work, camera string, ttl time.Duration, working func(string) bool) bool {
+	held, err := utils.ClaimLease(work+":"+camera, ttl)
+	if err != nil {
+		log.Printf("⚠️ Lease to %s camera %s unknown: %v", work, camera, err)
+		return working(camera)
+	}
+	return held
+}
+
+func loadCamera(c *gin.Context) (*models.Camera, bool) {
+	id, err := gocql.ParseUUID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid camera id"})
+		return nil, false
+	}
+	cam, err := db.GetCamera(id)
+	if err == gocql.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Camera not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch camera"})
+		return nil, false
+	}
+	return cam, true
+}
+
+// applyCameraRequest validates req and copies its fields onto cam
+func applyCameraRequest(c *gin.Context, cam *models.Camera, req CameraRequest) bool {
+	if req.Name != nil {
+		if !cameraName.MatchString(*req.Name) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name must be lowercase letters, digits, '-' or '_'"})
+			return false
+		}
+		taken, err := cameraNameTaken(*req.Name, cam.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check camera name"})
+			return false
+		}
+		if taken {
+			c.JSON(http.StatusConflict, gin.H{"error": "A camera with this name already exists"})
+			return false
+		}
+		cam.Name = *req.Name
+	}
+	if req.SourceURL != nil {
+		if _, err := stream.NewSource(*req.SourceURL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source_url: " + err.Error()})
+			return false
+		}
+		if u, _ := url.Parse(*req.SourceURL); u.User != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "source_url must not contain credentials, use credentials_ref"})
+			return false
+		}
+		cam.SourceURL = *req.SourceURL
+	}
+	if req.CredentialsRef != nil {
+		if *req.CredentialsRef != "" && !credentialsRef.MatchString(*req.CredentialsRef) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "credentials_ref must be letters, digits or '_'"})
+			return false
+		}
+		cam.CredentialsRef = *req.CredentialsRef
+	}
+	if req.Recording != nil {
+		switch *req.Recording {
+		case models.RecordingOff, models.RecordingMotion, models.RecordingContinuous:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "recording must be off, motion or continuous"})
+			return false
+		}
+		cam.Recording = *req.Recording
+	}
+	if req.RetentionDays != nil {
+		if *req.RetentionDays < 0 || *req.RetentionDays > maxRetentionDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "retention_days must be between 0 and 3650"})
+			return false
+		}
+		cam.RetentionDays = *req.RetentionDays
+	}
+	if req.PublishFPS != nil {
+		if *req.PublishFPS < 0 || *req.PublishFPS > maxPublishFPS {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "publish_fps must be between 0 and 10"})
+			return false
+		}
+		cam.PublishFPS = *req.PublishFPS
+	}
+	if req.BatchID != nil {
+		if len(*req.BatchID) > 128 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "batch_id is at most 128 characters"})
+			return false
+		}
+		cam.BatchID = *req.BatchID
+	}
+	if req.PTZDriver != nil {
+		if *req.PTZDriver != "" && !slices.Contains(ptz.Drivers(), *req.PTZDriver) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ptz_driver must be one of " + strings.Join(ptz.Drivers(), ", ")})
+			return false
+		}
+		cam.PTZDriver = *req.PTZDriver
+	}
+	if req.PTZURL != nil {
+		if u, err := url.Parse(*req.PTZURL); *req.PTZURL != "" && (err != nil || u.Host == "" || u.User != nil) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ptz_url must be an absolute URL without credentials"})
+			return false
+		}
+		cam.PTZURL = *req.PTZURL
+	}
+	if req.PTZProfile != nil {
+		cam.PTZProfile = *req.PTZProfile
+	}
+	if req.Location != nil {
+		cam.Location = *req.Location
+	}
+	if req.Enabled != nil {
+		cam.Enabled = *req.Enabled
+	}
+	return true
+}
+
+func cameraNameTaken(name string, self gocql.UUID) (bool, error) {
+	cameras, err := db.ListCameras()
+	if err != nil {
+		return false, err
+	}
+	for _, cam := range cameras {
+		if cam.Name == name && cam.ID != self {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,273 @@
+package routes
+
+import (
+	"camera/db"
+	"camera/models"
+	"camera/recording"
+	"camera/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"shared/auth"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocql/gocql"
+)
+
+const (
+	// maxClipRange bounds the time range of one clip listing
+	maxClipRange     = 7 * 24 * time.Hour
+	maxRetentionDays = 3650
+	// recordingLease is how long an instance keeps recording a camera without
+	// renewing its lease; leases are renewed on every camera reload
+	recordingLease = 90 * time.Second
+)
+
+var (
+	// Clips is where recordings are kept
+	Clips recording.Store
+	// Recorders runs the recorders of this instance
+	Recorders *recording.Manager
+)
+
+// ListClips returns the clips of a camera overlapping ?from= and ?to=
+// (RFC 3339, default the last 24 hours)
+func ListClips(c *gin.Context) {
+	cam, ok := viewableCamera(c)
+	if !ok {
+		return
+	}
+	to := time.Now()
+	from := to.Add(-24 * time.Hour)
+	var err error
+	if raw := c.Query("to"); raw != "" {
+		if to, err = time.Parse(time.RFC3339, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 time"})
+			return
+		}
+		from = to.Add(-24 * time.Hour)
+	}
+	if raw := c.Query("from"); raw != "" {
+		if from, err = time.Parse(time.RFC3339, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 time"})
+			return
+		}
+	}
+	if !from.Before(to) || to.Sub(from) > maxClipRange {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to, at most 7 days apart"})
+		return
+	}
+
+	// A clip started before from may still run into the range
+	clips, err := db.ListClips(cam.ID, from.Add(-recording.MaxSegment), to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list clips"})
+		return
+	}
+	overlapping := []models.Clip{}
+	for _, clip := range clips {
+		if clip.EndedAt.After(from) || !clip.StartedAt.Before(from) {
+			overlapping = append(overlapping, clip)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"camera": cam.Name, "from": from, "to": to, "clips": overlapping})
+}
+
+// DownloadClip streams one clip as MJPEG
+func DownloadClip(c *gin.Context) {
+	cam, ok := viewableCamera(c)
+	if !ok {
+		return
+	}
+	clipID, err := gocql.ParseUUID(c.Param("clip"))
+	if err != nil || clipID.Version() != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid clip id"})
+		return
+	}
+	clip, err := db.GetClip(cam.ID, clipID)
+	if err == gocql.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Clip not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch clip"})
+		return
+	}
+
+	body, size, err := Clips.Open(c.Request.Context(), clip.ObjectKey)
+	if errors.Is(err, recording.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Clip has expired"})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to open clip %s: %v", clip.ObjectKey, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read clip"})
+		return
+	}
+	defer body.Close()
+
+	filename := fmt.Sprintf("%s-%s.mjpeg", cam.Name, clip.StartedAt.UTC().Format("20060102-150405"))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("X-Clip-Frames", strconv.Itoa(clip.Frames))
+	c.DataFromReader(http.StatusOK, size, "video/x-motion-jpeg", body, nil)
+}
+
+// saveClip indexes a clip a recorder has stored
+func saveClip(stored recording.Clip) {
+	cameraID, err := gocql.ParseUUID(stored.CameraID)
+	if err != nil {
+		log.Printf("❌ Clip %s has an invalid camera id: %v", stored.Key, err)
+		return
+	}
+	clip := &models.Clip{
+		CameraID:  cameraID,
+		StartedAt: stored.Start,
+		EndedAt:   stored.End,
+		ObjectKey: stored.Key,
+		Size:      stored.Size,
+		Frames:    stored.Frames,
+		Trigger:   stored.Trigger,
+	}
+	if err := db.InsertClip(clip); err != nil {
+		// Unindexed, the clip could never be listed or expired
+		log.Printf("❌ Failed to index clip of camera %s: %v", stored.Camera, err)
+		if err := Clips.Delete(context.Background(), stored.Key); err != nil {
+			log.Printf("❌ Failed to delete unindexed clip %s: %v", stored.Key, err)
+		}
+	}
+}
+
+// InitRecording sets up Clips and Recorders from the environment
+func InitRecording() error {
+	store, err := recording.StoreFromEnv()
+	if err != nil {
+		return err
+	}
+	Clips = store
+	Recorders = recording.NewManager(store, saveClip)
+	return nil
+}
+
+// syncRecorders records the cameras of the registry that have recording on.
+// Each camera is recorded by the one instance holding its lease.
+func syncRecorders(cameras []models.Camera) {
+	if Recorders == nil {
+		return
+	}
+	base := recording.ConfigFromEnv()
+	var configs []recording.Config
+	for _, cam := range cameras {
+		if !cam.Enabled || (cam.Recording != models.RecordingMotion && cam.Recording != models.RecordingContinuous) {
+			continue
+		}
+		if !holdsLease("record", cam.Name, recordingLease, Recorders.Recording) {
+			continue
+		}
+		cfg := base
+		cfg.Camera = cam.Name
+		cfg.CameraID = cam.ID.String()
+		cfg.Mode = cam.Recording
+		configs = append(configs, cfg)
+	}
+	Recorders.Sync(Streams, configs)
+}
+
+// RetentionInterval returns how often expired clips are deleted
+// (RETENTION_INTERVAL_SECONDS, default one hour)
+func RetentionInterval() time.Duration {
+	return envSeconds("RETENTION_INTERVAL_SECONDS", 3600)
+}
+
+// StartRetention deletes clips past their camera's retention every interval.
+// Clips are dropped a whole day bucket at a time, once the entire day is older
+// than the retention; clips of deleted cameras follow the default retention.
+func StartRetention(interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			if !utils.ClaimRun("retention", interval) {
+				continue
+			}
+			if err := expireClips(time.Now()); err != nil {
+				log.Printf("❌ Clip retention failed: %v", err)
+			}
+		}
+	}()
+}
+
+func expireClips(now time.Time) error {
+	cameras, err := db.ListCameras()
+	if err != nil {
+		return err
+	}
+	retention := make(map[gocql.UUID]int, len(cameras))
+	for _, cam := range cameras {
+		retention[cam.ID] = cam.RetentionDays
+	}
+	buckets, err := db.DayBuckets()
+	if err != nil {
+		return err
+	}
+
+	for cameraID, days := range buckets {
+		keep := retention[cameraID]
+		if keep <= 0 {
+			keep = defaultRetentionDays()
+		}
+		cutoff := db.DayBucket(now.AddDate(0, 0, -keep))
+		for _, bucket := range days {
+			// Buckets sort as dates; the cutoff day itself still holds recent clips
+			if bucket >= cutoff {
+				continue
+			}
+			if err := expireBucket(cameraID, bucket); err != nil {
+				log.Printf("❌ Failed to expire clips of %s on %s: %v", cameraID, bucket, err)
+			}
+		}
+	}
+	return nil
+}
+
+func expireBucket(cameraID gocql.UUID, bucket string) error {
+	clips, err := db.ListBucketClips(cameraID, bucket)
+	if err != nil {
+		return err
+	}
+	for _, clip := range clips {
+		if err := Clips.Delete(context.Background(), clip.ObjectKey); err != nil {
+			// The bucket stays indexed and is retried on the next run
+			return err
+		}
+	}
+	if err := db.DeleteDayBucket(cameraID, bucket); err != nil {
+		return err
+	}
+	log.Printf("🗑️ Expired %d clips of %s from %s", len(clips), cameraID, bucket)
+	return nil
+}
+
+// defaultRetentionDays is RECORDING_RETENTION_DAYS, default 7
+func defaultRetentionDays() int {
+	if days, err := strconv.Atoi(os.Getenv("RECORDING_RETENTION_DAYS")); err == nil && days > 0 {
+		return days
+	}
+	return 7
+}
+
+// viewableCamera loads the camera of the request and checks the caller may view it
+func viewableCamera(c *gin.Context) (*models.Camera, bool) {
+	cam, ok := loadCamera(c)
+	if !ok {
+		return nil, false
+	}
+	permission := "camera:view:" + cam.Name
+	if !auth.ClaimsFrom(c).Can(permission) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied", "permission": permission})
+		return nil, false
+	}
+	return cam, true
+}
@@ -0,0 +1,54 @@
+package routes
+
+import (
+	"camera/frames"
+	"camera/models"
+	"time"
+)
+
+const (
+	// maxPublishFPS bounds how many frames per second a camera sends to inspection
+	maxPublishFPS = 10
+	// samplingLease is how long an instance keeps publishing a camera without
+	// renewing its lease; leases are renewed on every camera reload
+	samplingLease = 90 * time.Second
+)
+
+var (
+	// FramePublisher sends sampled frames to the inspection service
+	FramePublisher *frames.KafkaPublisher
+	// Samplers runs the frame samplers of this instance
+	Samplers *frames.Manager
+)
+
+// InitFramePublishing sets up FramePublisher and Samplers from the environment
+func InitFramePublishing() {
+	FramePublisher = frames.NewKafkaPublisher()
+	Samplers = frames.NewManager(FramePublisher)
+}
+
+// syncSamplers publishes the frames of the cameras that have publish_fps set.
+// Each camera is published by the one instance holding its lease, so the
+// inspection service does not get every frame twice.
+func syncSamplers(cameras []models.Camera) {
+	if Samplers == nil {
+		return
+	}
+	var configs []frames.Config
+	for _, cam := range cameras {
+		if !cam.Enabled || cam.PublishFPS <= 0 {
+			continue
+		}
+		if !holdsLease("publish", cam.Name, samplingLease, Samplers.Sampling) {
+			continue
+		}
+		configs = append(configs, frames.Config{
+			Camera:   cam.Name,
+			CameraID: cam.ID.String(),
+			Location: cam.Location,
+			BatchID:  cam.BatchID,
+			FPS:      cam.PublishFPS,
+		})
+	}
+	Samplers.Sync(Streams, configs)
+}
@@ -0,0 +1,227 @@
+package routes
+
+import (
+	"camera/db"
+	"camera/inspection"
+	"camera/models"
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocql/gocql"
+)
+
+const (
+	// maxDefectRange bounds the time range of one defect query
+	maxDefectRange   = 31 * 24 * time.Hour
+	defaultDefects   = 500
+	maxDefects       = 5000
+	maxSummaryDefect = 100000
+	// unassignedLine holds results with neither a line nor a known camera
+	unassignedLine = "unassigned"
+)
+
+// Shifts groups defects in the per shift summary
+var Shifts inspection.Shifts
+
+// defectQuery is the filter shared by ListDefects and DefectSummary
+type defectQuery struct {
+	line, batchID, cameraID, class string
+	minConfidence                  float64
+	from, to                       time.Time
+}
+
+// ListDefects returns defects of a line (?line=), a batch (?batch_id=) or a
+// camera (?camera_id=, on the camera's line) captured between ?from= and ?to=
+// (RFC 3339, default the last 24 hours), optionally of one ?class= and at
+// least ?min_confidence=
+func ListDefects(c *gin.Context) {
+	q, ok := parseDefectQuery(c)
+	if !ok {
+		return
+	}
+	limit := defaultDefects
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxDefects {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 5000"})
+			return
+		}
+		limit = n
+	}
+
+	defects, truncated, err := q.run(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query defects"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"defects": defects, "count": len(defects), "truncated": truncated})
+}
+
+// DefectSummary counts the defects matching the ListDefects filters per shift
+// and class
+func DefectSummary(c *gin.Context) {
+	q, ok := parseDefectQuery(c)
+	if !ok {
+		return
+	}
+	defects, truncated, err := q.run(maxSummaryDefect)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query defects"})
+		return
+	}
+
+	type shiftCount struct {
+		Shift     string         `json:"shift"`
+		StartedAt time.Time      `json:"started_at"`
+		Total     int            `json:"total"`
+		Classes   map[string]int `json:"classes"`
+	}
+	byShift := make(map[time.Time]*shiftCount)
+	for _, d := range defects {
+		name, start := Shifts.At(d.CapturedAt)
+		count, ok := byShift[start]
+		if !ok {
+			count = &shiftCount{Shift: name, StartedAt: start, Classes: map[string]int{}}
+			byShift[start] = count
+		}
+		count.Total++
+		count.Classes[d.Class]++
+	}
+	shifts := make([]*shiftCount, 0, len(byShift))
+	for _, count := range byShift {
+		shifts = append(shifts, count)
+	}
+	sort.Slice(shifts, func(i, j int) bool { return shifts[i].StartedAt.Before(shifts[j].StartedAt) })
+
+	c.JSON(http.StatusOK, gin.H{"shifts": shifts, "total": len(defects), "truncated": truncated, "from": q.from, "to": q.to})
+}
+
+func parseDefectQuery(c *gin.Context) (defectQuery, bool) {
+	q := defectQuery{line: c.Query("line"), batchID: c.Query("batch_id"), cameraID: c.Query("camera_id"), class: c.Query("class")}
+	if q.line == "" && q.batchID == "" && q.cameraID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "line, batch_id or camera_id is required"})
+		return q, false
+	}
+	if raw := c.Query("min_confidence"); raw != "" {
+		var err error
+		if q.minConfidence, err = strconv.ParseFloat(raw, 64); err != nil || q.minConfidence < 0 || q.minConfidence > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_confidence must be between 0 and 1"})
+			return q, false
+		}
+	}
+
+	q.to = time.Now()
+	q.from = q.to.Add(-24 * time.Hour)
+	var err error
+	if raw := c.Query("to"); raw != "" {
+		if q.to, err = time.Parse(time.RFC3339, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 time"})
+			return q, false
+		}
+		q.from = q.to.Add(-24 * time.Hour)
+	}
+	if raw := c.Query("from"); raw != "" {
+		if q.from, err = time.Parse(time.RFC3339, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 time"})
+			return q, false
+		}
+	}
+	if !q.from.Before(q.to) || q.to.Sub(q.from) > maxDefectRange {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to, at most 31 days apart"})
+		return q, false
+	}
+
+	// A camera alone is looked up on its line
+	if q.line == "" && q.batchID == "" {
+		id, err := gocql.ParseUUID(q.cameraID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid camera id"})
+			return q, false
+		}
+		cam, err := db.GetCamera(id)
+		if err == gocql.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Camera not found"})
+			return q, false
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch camera"})
+			return q, false
+		}
+		q.line = lineOf(cam)
+	}
+	return q, true
+}
+
+// run reads the matching defects, at most limit of them; truncated reports
+// whether there are more
+func (q defectQuery) run(limit int) ([]models.Defect, bool, error) {
+	filter := models.DefectFilter{Line: q.line, CameraID: q.cameraID, Class: q.class, MinConfidence: q.minConfidence}
+	if q.batchID != "" {
+		return db.ListBatchDefects(q.batchID, q.from, q.to, filter, limit)
+	}
+	return db.ListLineDefects(q.line, q.from, q.to, filter, limit)
+}
+
+// StartInspectionConsumer stores the results of the defect detector as they
+// arrive on Kafka
+func StartInspectionConsumer(ctx context.Context) {
+	go func() {
+		config := inspection.ConsumerOptionsFromEnv()
+		for {
+			err := inspection.Consume(ctx, config, storeInspectionResult)
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("❌ Inspection consumer stopped: %v — restarting in %s", err, 10*time.Second)
+			time.Sleep(10 * time.Second)
+		}
+	}()
+}
+
+// storeInspectionResult saves the defects of one result
+func storeInspectionResult(result inspection.Result) error {
+	line := result.Line
+	if line == "" {
+		line = unassignedLine
+		if id, err := gocql.ParseUUID(result.CameraID); err == nil {
+			cam, err := db.GetCamera(id)
+			if err != nil && err != gocql.ErrNotFound {
+				return err
+			}
+			if cam != nil {
+				line = lineOf(cam)
+			}
+		}
+	}
+
+	defects := make([]models.Defect, 0, len(result.Defects))
+	for i, d := range result.Defects {
+		defects = append(defects, models.Defect{
+			ID:           result.DefectID(i),
+			Line:         line,
+			CameraID:     result.CameraID,
+			BatchID:      result.BatchID,
+			Class:        d.Class,
+			Confidence:   d.Confidence,
+			BBox:         d.BBox,
+			Sequence:     result.Sequence,
+			CapturedAt:   result.CapturedAt,
+			InspectedAt:  result.InspectedAt,
+			ModelVersion: result.ModelVersion,
+		})
+	}
+	return db.InsertDefects(defects)
+}
+
+// lineOf is the production line of a camera: its location
+func lineOf(cam *models.Camera) string {
+	if cam.Location == "" {
+		return unassignedLine
+	}
+	return cam.Location
+}
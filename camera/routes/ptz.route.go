@@ -0,0 +1,238 @@
+package routes
+
+import (
+	"camera/db"
+	"camera/models"
+	"camera/ptz"
+	"camera/utils"
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"shared/auth"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ptzTimeout bounds one command to the camera
+	ptzTimeout      = 10 * time.Second
+	defaultMoveTime = 500 * time.Millisecond
+	maxMoveTime     = 5 * time.Second
+)
+
+type PTZRequest struct {
+	// Action is move, stop, goto_preset, set_preset or release
+	Action string  `json:"action" binding:"required"`
+	Pan    float64 `json:"pan"`
+	Tilt   float64 `json:"tilt"`
+	Zoom   float64 `json:"zoom"`
+	// DurationMS is how long a move lasts unless stopped (default 500, at most 5000)
+	DurationMS int `json:"duration_ms"`
+	// Preset is the token to go to
+	Preset string `json:"preset"`
+	// Name is the name of a new preset
+	Name string `json:"name"`
+}
+
+// PTZ moves a PTZ camera or manages its presets. The first command gives the
+// operator control of the camera for PTZ_CONTROL_SECONDS (default 60), renewed
+// by every command; others get a 409 until it expires or is released.
+func PTZ(c *gin.Context) {
+	cam, driver, ok := ptzCamera(c)
+	if !ok {
+		return
+	}
+	var req PTZRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	details, ok := validatePTZ(c, &req)
+	if !ok {
+		return
+	}
+
+	operator := operatorOf(c)
+	if req.Action == "release" {
+		if err := utils.ReleaseControl(cam.ID.String(), operator); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to release the camera"})
+			return
+		}
+		audit(c, "ptz.release", cam.Name, nil)
+		c.JSON(http.StatusOK, gin.H{"message": "Camera released"})
+		return
+	}
+
+	control := envSeconds("PTZ_CONTROL_SECONDS", 60)
+	holder, left, err := utils.ClaimControl(cam.ID.String(), operator, control)
+	if err != nil {
+		log.Printf("❌ Failed to claim control of camera %s: %v", cam.Name, err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Camera control is unavailable"})
+		return
+	}
+	if holder != operator {
+		retryAfter := int(math.Ceil(left.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusConflict, gin.H{"error": "Camera is controlled by another operator", "holder": holder, "retry_after": retryAfter})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ptzTimeout)
+	defer cancel()
+	response := gin.H{"message": "Command sent", "control_expires_in": int(control.Seconds())}
+	switch req.Action {
+	case "move":
+		err = driver.ContinuousMove(ctx, ptz.Velocity{Pan: req.Pan, Tilt: req.Tilt, Zoom: req.Zoom}, time.Duration(req.DurationMS)*time.Millisecond)
+	case "stop":
+		err = driver.Stop(ctx)
+	case "goto_preset":
+		err = driver.GotoPreset(ctx, req.Preset)
+	case "set_preset":
+		var token string
+		if token, err = driver.SetPreset(ctx, req.Name); err == nil {
+			response["token"] = token
+			details["token"] = token
+		}
+	}
+
+	if err != nil {
+		details["error"] = err.Error()
+	}
+	audit(c, "ptz."+req.Action, cam.Name, details)
+	if err != nil {
+		log.Printf("⚠️ PTZ %s on camera %s failed: %v", req.Action, cam.Name, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Camera rejected the command"})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// PTZPresets lists the presets saved on a PTZ camera
+func PTZPresets(c *gin.Context) {
+	cam, driver, ok := ptzCamera(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ptzTimeout)
+	defer cancel()
+	presets, err := driver.Presets(ctx)
+	if err != nil {
+		log.Printf("⚠️ Failed to list presets of camera %s: %v", cam.Name, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Camera did not return its presets"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"presets": presets})
+}
+
+// ListAuditLog returns one day of operator actions (day=YYYY-MM-DD, default today UTC)
+func ListAuditLog(c *gin.Context) {
+	day := c.DefaultQuery("day", time.Now().UTC().Format("2006-01-02"))
+	if _, err := time.Parse("2006-01-02", day); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "day must be YYYY-MM-DD"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+
+	entries, err := db.ListAudit(day, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read audit log"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"day": day, "entries": entries})
+}
+
+// ptzCamera loads the camera of the request, checks the caller may steer it
+// (camera:ptz:<name>) and opens its driver
+func ptzCamera(c *gin.Context) (*models.Camera, ptz.Driver, bool) {
+	cam, ok := loadCamera(c)
+	if !ok {
+		return nil, nil, false
+	}
+	permission := "camera:ptz:" + cam.Name
+	if !auth.ClaimsFrom(c).Can(permission) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied", "permission": permission})
+		return nil, nil, false
+	}
+	if cam.PTZDriver == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Camera has no PTZ control"})
+		return nil, nil, false
+	}
+
+	user, password, err := utils.Credentials(*cam)
+	if err == nil {
+		var driver ptz.Driver
+		driver, err = ptz.Open(cam.PTZDriver, ptz.Config{URL: cam.PTZURL, Username: user, Password: password, Profile: cam.PTZProfile})
+		if err == nil {
+			return cam, driver, true
+		}
+	}
+	log.Printf("❌ PTZ of camera %s is misconfigured: %v", cam.Name, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "PTZ of this camera is misconfigured"})
+	return nil, nil, false
+}
+
+// validatePTZ checks req and returns the details to audit
+func validatePTZ(c *gin.Context, req *PTZRequest) (map[string]string, bool) {
+	details := map[string]string{}
+	switch req.Action {
+	case "move":
+		for _, speed := range []float64{req.Pan, req.Tilt, req.Zoom} {
+			if speed < -1 || speed > 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "pan, tilt and zoom must be between -1 and 1"})
+				return nil, false
+			}
+		}
+		if req.Pan == 0 && req.Tilt == 0 && req.Zoom == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "a move needs a pan, tilt or zoom speed"})
+			return nil, false
+		}
+		if req.DurationMS == 0 {
+			req.DurationMS = int(defaultMoveTime.Milliseconds())
+		}
+		if req.DurationMS < 0 || req.DurationMS > int(maxMoveTime.Milliseconds()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duration_ms must be between 1 and 5000"})
+			return nil, false
+		}
+		details["pan"] = strconv.FormatFloat(req.Pan, 'f', -1, 64)
+		details["tilt"] = strconv.FormatFloat(req.Tilt, 'f', -1, 64)
+		details["zoom"] = strconv.FormatFloat(req.Zoom, 'f', -1, 64)
+		details["duration_ms"] = strconv.Itoa(req.DurationMS)
+	case "goto_preset":
+		if req.Preset == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "preset is required"})
+			return nil, false
+		}
+		details["preset"] = req.Preset
+	case "set_preset":
+		if req.Name == "" || len(req.Name) > 64 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name is required, at most 64 characters"})
+			return nil, false
+		}
+		details["name"] = req.Name
+	case "stop", "release":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action must be move, stop, goto_preset, set_preset or release"})
+		return nil, false
+	}
+	return details, true
+}
+
+// operatorOf names the caller in locks and the audit log
+func operatorOf(c *gin.Context) string {
+	claims := auth.ClaimsFrom(c)
+	if claims.Email != "" {
+		return claims.Email
+	}
+	return claims.UserID
+}
+
+func audit(c *gin.Context, action, target string, details map[string]string) {
+	db.RecordAudit(operatorOf(c), action, target, c.ClientIP(), details)
+}
@@ -0,0 +1,124 @@
+package routes
+
+import (
+	"camera/db"
+	"camera/stream"
+	"camera/utils"
+	"context"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"shared/auth"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// snapshotTimeout bounds how long we wait for a camera to produce a frame
+	snapshotTimeout = 10 * time.Second
+	thumbnailWidth  = 320
+)
+
+// Snapshot returns the current frame of a camera as a JPEG
+func Snapshot(c *gin.Context) {
+	cam, ok := viewableCamera(c)
+	if !ok {
+		return
+	}
+	ch, ok := Streams.Channel(cam.Name)
+	if !ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "Camera is disabled"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), snapshotTimeout)
+	defer cancel()
+	frame, err := ch.Snapshot(ctx)
+	if err != nil {
+		log.Printf("⚠️ Snapshot of camera %s failed: %v", cam.Name, err)
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Camera did not return a frame"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/jpeg", frame)
+}
+
+// Grid returns the latest thumbnail of every enabled camera the caller may
+// view, as data URIs. Cameras without a thumbnail yet have none.
+func Grid(c *gin.Context) {
+	cameras, err := db.ListCameras()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list cameras"})
+		return
+	}
+
+	claims := auth.ClaimsFrom(c)
+	grid := []gin.H{}
+	for _, cam := range cameras {
+		if !cam.Enabled || !claims.Can("camera:view:"+cam.Name) {
+			continue
+		}
+		cell := gin.H{"id": cam.ID, "name": cam.Name, "location": cam.Location, "thumbnail": nil, "captured_at": nil}
+		thumb, at, err := utils.Thumbnail(cam.Name)
+		if err == nil {
+			cell["thumbnail"] = "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(thumb)
+			cell["captured_at"] = at
+		} else if err != redis.Nil {
+			log.Printf("⚠️ Failed to read thumbnail of camera %s: %v", cam.Name, err)
+		}
+		grid = append(grid, cell)
+	}
+	c.JSON(http.StatusOK, gin.H{"cameras": grid})
+}
+
+// ThumbnailInterval returns how often thumbnails are refreshed
+// (THUMBNAIL_INTERVAL_SECONDS, default 60)
+func ThumbnailInterval() time.Duration {
+	return envSeconds("THUMBNAIL_INTERVAL_SECONDS", 60)
+}
+
+// StartThumbnailer refreshes the thumbnail of every camera every interval.
+// With several instances running, each camera is refreshed by only one of them.
+func StartThumbnailer(interval time.Duration) {
+	go func() {
+		refreshThumbnails(interval)
+		for range time.Tick(interval) {
+			refreshThumbnails(interval)
+		}
+	}()
+}
+
+func refreshThumbnails(interval time.Duration) {
+	var wg sync.WaitGroup
+	for _, name := range Streams.Names() {
+		ch, ok := Streams.Channel(name)
+		if !ok || !utils.ClaimRun("thumb:"+name, interval) {
+			continue
+		}
+		wg.Add(1)
+		go func(ch *stream.Channel) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), snapshotTimeout)
+			defer cancel()
+			frame, err := ch.Snapshot(ctx)
+			if err != nil {
+				log.Printf("⚠️ Thumbnail of camera %s skipped: %v", ch.Name, err)
+				return
+			}
+			thumb, err := stream.Thumbnail(frame, thumbnailWidth)
+			if err != nil {
+				log.Printf("⚠️ Thumbnail of camera %s skipped: %v", ch.Name, err)
+				return
+			}
+			// Kept for a few intervals, so a camera that stops answering drops out of the grid
+			if err := utils.SaveThumbnail(ch.Name, thumb, time.Now(), 3*interval); err != nil {
+				log.Printf("❌ Failed to save thumbnail of camera %s: %v", ch.Name, err)
+			}
+		}(ch)
+	}
+	wg.Wait()
+}
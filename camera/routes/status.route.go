@@ -0,0 +1,152 @@
+package routes
+
+import (
+	"camera/db"
+	"camera/health"
+	"camera/models"
+	"camera/stream"
+	"camera/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"shared/auth"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CameraStatus returns the health of every camera the caller may view
+func CameraStatus(c *gin.Context) {
+	cameras, err := db.ListCameras()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list cameras"})
+		return
+	}
+	statuses, err := db.ListCameraStatus()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read camera status"})
+		return
+	}
+
+	claims := auth.ClaimsFrom(c)
+	result := []gin.H{}
+	for _, cam := range cameras {
+		if !claims.Can("camera:view:" + cam.Name) {
+			continue
+		}
+		status, ok := statuses[cam.ID]
+		if !ok {
+			status = models.CameraStatus{CameraID: cam.ID, Status: models.StatusUnknown}
+		}
+		status.Name = cam.Name
+		result = append(result, gin.H{"camera": status, "location": cam.Location, "enabled": cam.Enabled})
+	}
+	c.JSON(http.StatusOK, gin.H{"cameras": result})
+}
+
+// ProbeInterval returns how often cameras are checked (CAMERA_PROBE_INTERVAL_SECONDS, default 30)
+func ProbeInterval() time.Duration {
+	return envSeconds("CAMERA_PROBE_INTERVAL_SECONDS", 30)
+}
+
+// StartProber checks every enabled camera every interval, records the result in
+// camera_status and alerts the camera's viewers when it goes offline or
+// recovers. With several instances running, each camera is probed by one of them.
+func StartProber(interval time.Duration) {
+	offlineAfter, err := strconv.Atoi(os.Getenv("CAMERA_OFFLINE_AFTER"))
+	if err != nil || offlineAfter < 1 {
+		offlineAfter = 2
+	}
+	go func() {
+		probeCameras(interval, offlineAfter)
+		for range time.Tick(interval) {
+			probeCameras(interval, offlineAfter)
+		}
+	}()
+}
+
+func probeCameras(interval time.Duration, offlineAfter int) {
+	cameras, err := db.ListCameras()
+	if err != nil {
+		log.Printf("❌ Failed to list cameras to probe: %v", err)
+		return
+	}
+	statuses, err := db.ListCameraStatus()
+	if err != nil {
+		log.Printf("❌ Failed to read camera status: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, cam := range cameras {
+		if !cam.Enabled || !utils.ClaimRun("probe:"+cam.ID.String(), interval) {
+			continue
+		}
+		wg.Add(1)
+		go func(cam models.Camera, prev models.CameraStatus) {
+			defer wg.Done()
+			probeCamera(cam, prev, min(interval, snapshotTimeout), offlineAfter)
+		}(cam, statuses[cam.ID])
+	}
+	wg.Wait()
+}
+
+func probeCamera(cam models.Camera, prev models.CameraStatus, timeout time.Duration, offlineAfter int) {
+	var latency time.Duration
+	var err error
+	if ch, ok := Streams.Channel(cam.Name); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		latency, err = ch.Probe(ctx)
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = probeTimeoutError(ch, timeout)
+		}
+	} else {
+		// The source could not be loaded, e.g. its credentials are missing
+		err = errors.New("camera source is not configured")
+	}
+
+	prev.CameraID = cam.ID
+	prev.Name = cam.Name
+	next, transition := health.Record(prev, latency, err, time.Now(), offlineAfter)
+	if err := db.SaveCameraStatus(&next); err != nil {
+		log.Printf("❌ Failed to save status of camera %s: %v", cam.Name, err)
+	}
+
+	var alert utils.Notification
+	switch transition {
+	case health.WentOffline:
+		log.Printf("🔴 Camera %s is offline: %s", cam.Name, next.Error)
+		alert = utils.Notification{Title: "Camera offline", Type: "error",
+			Content: fmt.Sprintf("Camera %s (%s) stopped sending video: %s", cam.Name, cam.Location, next.Error)}
+	case health.Recovered:
+		log.Printf("🟢 Camera %s is back online", cam.Name)
+		alert = utils.Notification{Title: "Camera online", Type: "info",
+			Content: fmt.Sprintf("Camera %s (%s) is back online", cam.Name, cam.Location)}
+	default:
+		return
+	}
+	alert.Permission = "camera:view:" + cam.Name
+	if err := utils.Notify(alert); err != nil {
+		log.Printf("❌ Failed to send alert for camera %s: %v", cam.Name, err)
+	}
+}
+
+func probeTimeoutError(ch *stream.Channel, timeout time.Duration) error {
+	if err := ch.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("no frame within %s", timeout)
+}
+
+func envSeconds(key string, fallback int) time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv(key)); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return time.Duration(fallback) * time.Second
+}
@@ -0,0 +1,154 @@
+package stream
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// viewerBuffer is how many frames a slow viewer may lag before frames are dropped for it
+	viewerBuffer = 2
+	minBackoff   = time.Second
+	maxBackoff   = 30 * time.Second
+)
+
+// Channel fans the frames of one camera out to its viewers. The upstream
+// connection is opened for the first viewer, shared by everyone watching and
+// closed when the last one leaves.
+type Channel struct {
+	Name   string
+	source Source
+
+	mu      sync.Mutex
+	viewers map[chan []byte]struct{}
+	cancel  context.CancelFunc
+	latest  []byte
+	lastErr error
+
+	hlsMu sync.Mutex
+	hls   *hlsTranscoder
+}
+
+// NewChannel returns an idle channel reading from source
+func NewChannel(name string, source Source) *Channel {
+	return &Channel{Name: name, source: source, viewers: make(map[chan []byte]struct{})}
+}
+
+// Subscribe adds a viewer. Frames arrive on the returned channel, starting with
+// the latest one when the camera is already streaming; call leave when done.
+func (ch *Channel) Subscribe() (frames <-chan []byte, leave func()) {
+	return ch.subscribe(true)
+}
+
+func (ch *Channel) subscribe(withLatest bool) (<-chan []byte, func()) {
+	viewer := make(chan []byte, viewerBuffer)
+
+	ch.mu.Lock()
+	ch.viewers[viewer] = struct{}{}
+	if withLatest && ch.latest != nil {
+		viewer <- ch.latest
+	}
+	if ch.cancel == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		ch.cancel = cancel
+		go ch.run(ctx)
+	}
+	ch.mu.Unlock()
+
+	var once sync.Once
+	return viewer, func() { once.Do(func() { ch.unsubscribe(viewer) }) }
+}
+
+// Viewers returns how many viewers are watching
+func (ch *Channel) Viewers() int {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return len(ch.viewers)
+}
+
+// Err returns why the upstream connection last failed, if it has not
+// delivered a frame since
+func (ch *Channel) Err() error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return ch.lastErr
+}
+
+func (ch *Channel) unsubscribe(viewer chan []byte) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if _, ok := ch.viewers[viewer]; !ok {
+		return // already disconnected by Close
+	}
+	delete(ch.viewers, viewer)
+	close(viewer)
+	if len(ch.viewers) == 0 && ch.cancel != nil {
+		ch.cancel()
+		ch.cancel = nil
+		ch.latest = nil
+	}
+}
+
+// run keeps the upstream connection open, reconnecting with backoff, until ctx is done
+func (ch *Channel) run(ctx context.Context) {
+	backoff := minBackoff
+	for {
+		received := false
+		err := ch.source.Stream(ctx, func(frame []byte) {
+			received = true
+			ch.publish(ctx, frame)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if received {
+			backoff = minBackoff
+		}
+		ch.mu.Lock()
+		ch.lastErr = err
+		ch.mu.Unlock()
+		log.Printf("⚠️ Camera %s disconnected: %v — retrying in %s", ch.Name, err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+func (ch *Channel) publish(ctx context.Context, frame []byte) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	// A previous upstream may still be winding down after the last viewer left
+	if ctx.Err() != nil {
+		return
+	}
+	ch.latest = frame
+	ch.lastErr = nil
+	for viewer := range ch.viewers {
+		select {
+		case viewer <- frame:
+		default: // the viewer is behind, it skips this frame
+		}
+	}
+}
+
+// Close disconnects every viewer and the upstream connection
+func (ch *Channel) Close() {
+	ch.stopHLS()
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	for viewer := range ch.viewers {
+		delete(ch.viewers, viewer)
+		close(viewer)
+	}
+	if ch.cancel != nil {
+		ch.cancel()
+		ch.cancel = nil
+	}
+	ch.latest = nil
+}
@@ -0,0 +1,150 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// HLSPlaylist is the playlist file name in the HLS directory of a channel
+	HLSPlaylist = "index.m3u8"
+	// hlsIdleTimeout stops a transcoder once nobody fetched from it for this long
+	hlsIdleTimeout = 30 * time.Second
+)
+
+// ErrHLSNotReady is returned while the first segment is still being encoded
+var ErrHLSNotReady = errors.New("stream is starting")
+
+// hlsTranscoder encodes the frames of a channel into H.264 HLS segments with
+// ffmpeg. It is fed from the shared upstream like any other viewer.
+type hlsTranscoder struct {
+	dir     string
+	cancel  context.CancelFunc
+	lastUse time.Time
+}
+
+// HLS returns the directory holding the channel's playlist and segments. The
+// transcoder starts on first use and stops after hlsIdleTimeout without calls,
+// so callers should call HLS for every playlist or segment they serve. wait
+// bounds how long to wait for the playlist of a starting transcoder.
+func (ch *Channel) HLS(wait time.Duration) (string, error) {
+	ch.hlsMu.Lock()
+	t := ch.hls
+	if t == nil {
+		var err error
+		if t, err = ch.startHLS(); err != nil {
+			ch.hlsMu.Unlock()
+			return "", err
+		}
+		ch.hls = t
+	}
+	t.lastUse = time.Now()
+	ch.hlsMu.Unlock()
+
+	playlist := filepath.Join(t.dir, HLSPlaylist)
+	deadline := time.Now().Add(wait)
+	for {
+		if _, err := os.Stat(playlist); err == nil {
+			return t.dir, nil
+		}
+		if time.Now().After(deadline) {
+			return "", ErrHLSNotReady
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+func (ch *Channel) startHLS() (*hlsTranscoder, error) {
+	dir, err := os.MkdirTemp("", "hls-"+ch.Name+"-")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, FFmpegPath(),
+		"-nostdin", "-loglevel", "error",
+		"-use_wallclock_as_timestamps", "1", "-f", "mjpeg", "-i", "pipe:0",
+		"-c:v", "libx264", "-preset", "veryfast", "-tune", "zerolatency", "-pix_fmt", "yuv420p",
+		"-force_key_frames", "expr:gte(t,n_forced*2)",
+		"-f", "hls", "-hls_time", "2", "-hls_list_size", "6", "-hls_flags", "delete_segments+omit_endlist",
+		"-hls_segment_filename", filepath.Join(dir, "segment%05d.ts"),
+		filepath.Join(dir, HLSPlaylist))
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		cancel()
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	t := &hlsTranscoder{dir: dir, cancel: cancel, lastUse: time.Now()}
+
+	frames, leave := ch.Subscribe()
+	go func() {
+		defer leave()
+		defer stdin.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case frame, ok := <-frames:
+				if !ok {
+					return
+				}
+				if _, err := stdin.Write(frame); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(hlsIdleTimeout / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ch.hlsMu.Lock()
+				idle := time.Since(t.lastUse) > hlsIdleTimeout
+				ch.hlsMu.Unlock()
+				if idle {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	go func() {
+		if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+			log.Printf("⚠️ HLS transcoder of camera %s stopped: %v", ch.Name, err)
+		}
+		cancel()
+		os.RemoveAll(dir)
+		ch.hlsMu.Lock()
+		if ch.hls == t {
+			ch.hls = nil
+		}
+		ch.hlsMu.Unlock()
+	}()
+	return t, nil
+}
+
+func (ch *Channel) stopHLS() {
+	ch.hlsMu.Lock()
+	defer ch.hlsMu.Unlock()
+	if ch.hls != nil {
+		ch.hls.cancel()
+		ch.hls = nil
+	}
+}
@@ -0,0 +1,102 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Hub holds the channels of every configured camera
+type Hub struct {
+	mu       sync.RWMutex
+	channels map[string]*Channel
+	urls     map[string]string
+}
+
+// NewHub creates a channel for every name -> camera URL in sources
+func NewHub(sources map[string]string) (*Hub, error) {
+	h := &Hub{channels: make(map[string]*Channel), urls: make(map[string]string)}
+	return h, h.Sync(sources)
+}
+
+// Sync makes the hub serve exactly sources (name -> camera URL). Channels that
+// are gone or point somewhere else are closed, which disconnects their viewers.
+// Invalid sources are skipped and reported in the returned error.
+func (h *Hub) Sync(sources map[string]string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for name, ch := range h.channels {
+		if rawURL, ok := sources[name]; !ok || rawURL != h.urls[name] {
+			ch.Close()
+			delete(h.channels, name)
+			delete(h.urls, name)
+		}
+	}
+
+	var errs []error
+	for name, rawURL := range sources {
+		if _, ok := h.channels[name]; ok {
+			continue
+		}
+		source, err := NewSource(rawURL)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("camera %s: %w", name, err))
+			continue
+		}
+		h.channels[name] = NewChannel(name, source)
+		h.urls[name] = rawURL
+	}
+	return errors.Join(errs...)
+}
+
+// Channel returns the channel called name
+func (h *Hub) Channel(name string) (*Channel, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ch, ok := h.channels[name]
+	return ch, ok
+}
+
+// Names returns the channel names in order
+func (h *Hub) Names() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	names := make([]string, 0, len(h.channels))
+	for name := range h.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Close disconnects every channel
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, ch := range h.channels {
+		ch.Close()
+	}
+}
+
+// SourcesFromEnv reads CAMERA_SOURCES, a comma separated list of
+// name=url pairs such as "channel1=rtsp://10.0.0.5/stream1,channel2=http://10.0.0.6/mjpeg".
+// It only seeds an empty camera registry.
+func SourcesFromEnv() (map[string]string, error) {
+	sources := make(map[string]string)
+	for _, entry := range strings.Split(os.Getenv("CAMERA_SOURCES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, rawURL, ok := strings.Cut(entry, "=")
+		if !ok || name == "" || rawURL == "" {
+			return nil, fmt.Errorf("invalid CAMERA_SOURCES entry %q, want name=url", entry)
+		}
+		sources[strings.TrimSpace(name)] = strings.TrimSpace(rawURL)
+	}
+	return sources, nil
+}
@@ -0,0 +1,86 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"time"
+)
+
+// ErrChannelClosed is returned when a channel is removed while waiting on it
+var ErrChannelClosed = errors.New("channel closed")
+
+// Snapshot returns the current frame of the channel. It is immediate while the
+// camera is streaming; otherwise the camera is connected for the first frame.
+func (ch *Channel) Snapshot(ctx context.Context) ([]byte, error) {
+	frames, leave := ch.Subscribe()
+	defer leave()
+	select {
+	case frame, ok := <-frames:
+		if !ok {
+			return nil, ErrChannelClosed
+		}
+		return frame, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Probe waits for a fresh frame from the camera and returns how long it took.
+// An idle camera is connected for the probe, so that includes connecting.
+func (ch *Channel) Probe(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	frames, leave := ch.subscribe(false)
+	defer leave()
+	select {
+	case _, ok := <-frames:
+		if !ok {
+			return 0, ErrChannelClosed
+		}
+		return time.Since(start), nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// Thumbnail scales a JPEG frame down to width pixels, keeping its aspect ratio.
+// Frames narrower than width are only re-encoded.
+func Thumbnail(frame []byte, width int) ([]byte, error) {
+	src, err := jpeg.Decode(bytes.NewReader(frame))
+	if err != nil {
+		return nil, err
+	}
+	bounds := src.Bounds()
+	if bounds.Dx() < width {
+		width = bounds.Dx()
+	}
+	height := max(1, bounds.Dy()*width/bounds.Dx())
+
+	// Box filter: every thumbnail pixel averages the source pixels it covers
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+			var r, g, b, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, _ := src.At(sx, sy).RGBA()
+					r, g, b, n = r+uint64(pr), g+uint64(pg), b+uint64(pb), n+1
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(b / n >> 8), A: 0xFF})
+		}
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: 75}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
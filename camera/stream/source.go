@@ -0,0 +1,179 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// maxFrameSize bounds a single JPEG frame read from a camera
+const maxFrameSize = 8 << 20
+
+// Source produces the JPEG frames of one camera. Stream blocks, calling frame
+// for every frame, until ctx is done or the upstream connection fails.
+type Source interface {
+	Stream(ctx context.Context, frame func([]byte)) error
+}
+
+// NewSource picks the Source for a camera URL: rtsp(s):// goes through ffmpeg,
+// http(s):// must serve an MJPEG (multipart/x-mixed-replace) stream.
+func NewSource(rawURL string) (Source, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "rtsp", "rtsps":
+		return &RTSPSource{URL: rawURL}, nil
+	case "http", "https":
+		return &MJPEGSource{URL: rawURL}, nil
+	default:
+		return nil, fmt.Errorf("unsupported camera source scheme %q", u.Scheme)
+	}
+}
+
+// MJPEGSource reads an MJPEG stream over HTTP, as served by most IP cameras
+type MJPEGSource struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s *MJPEGSource) Stream(ctx context.Context, frame func([]byte)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return err
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("camera returned %s", resp.Status)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return fmt.Errorf("camera served %s, not an MJPEG stream", mediaType)
+	}
+	// Some cameras put the leading dashes in the boundary parameter
+	parts := multipart.NewReader(resp.Body, strings.TrimPrefix(params["boundary"], "--"))
+	for {
+		part, err := parts.NextPart()
+		if err != nil {
+			return err
+		}
+		// With a Content-Length the frame is complete without waiting for the
+		// next boundary, which saves a frame of latency
+		var data []byte
+		if n, convErr := strconv.Atoi(part.Header.Get("Content-Length")); convErr == nil && n > 0 && n <= maxFrameSize {
+			data = make([]byte, n)
+			_, err = io.ReadFull(part, data)
+		} else {
+			data, err = io.ReadAll(io.LimitReader(part, maxFrameSize))
+		}
+		if err != nil {
+			return err
+		}
+		if len(data) > 0 {
+			frame(data)
+		}
+	}
+}
+
+// RTSPSource decodes an RTSP stream with ffmpeg (FFMPEG_PATH, default "ffmpeg")
+// into JPEG frames
+type RTSPSource struct {
+	URL string
+}
+
+func (s *RTSPSource) Stream(ctx context.Context, frame func([]byte)) error {
+	cmd := exec.CommandContext(ctx, FFmpegPath(),
+		"-nostdin", "-loglevel", "error",
+		"-rtsp_transport", "tcp", "-i", s.URL,
+		"-an", "-f", "mjpeg", "-q:v", "5", "pipe:1")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	err = SplitJPEG(stdout, frame)
+	if waitErr := cmd.Wait(); waitErr != nil && ctx.Err() == nil {
+		return fmt.Errorf("ffmpeg: %v: %s", waitErr, strings.TrimSpace(stderr.String()))
+	}
+	return err
+}
+
+// FFmpegPath returns the ffmpeg binary to run
+func FFmpegPath() string {
+	if path := os.Getenv("FFMPEG_PATH"); path != "" {
+		return path
+	}
+	return "ffmpeg"
+}
+
+// SplitJPEG calls frame for every JPEG image (SOI to EOI marker) in a raw
+// concatenated stream, like ffmpeg's mjpeg output
+func SplitJPEG(r io.Reader, frame func([]byte)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 512<<10), maxFrameSize)
+	scanner.Split(splitJPEG)
+	for scanner.Scan() {
+		frame(bytes.Clone(scanner.Bytes()))
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+var (
+	jpegStart = []byte{0xFF, 0xD8}
+	jpegEnd   = []byte{0xFF, 0xD9}
+)
+
+func splitJPEG(data []byte, atEOF bool) (int, []byte, error) {
+	start := bytes.Index(data, jpegStart)
+	if start < 0 {
+		// Keep a trailing 0xFF, it may start the next marker
+		if len(data) > 0 && data[len(data)-1] == 0xFF {
+			return len(data) - 1, nil, nil
+		}
+		return len(data), nil, nil
+	}
+	end := bytes.Index(data[start+2:], jpegEnd)
+	if end >= 0 {
+		end += start + 2 + len(jpegEnd)
+		return end, data[start:end], nil
+	}
+	if atEOF {
+		if len(data) > 0 {
+			return 0, nil, errors.New("truncated JPEG frame")
+		}
+		return 0, nil, nil
+	}
+	return start, nil, nil
+}
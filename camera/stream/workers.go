@@ -0,0 +1,79 @@
+package stream
+
+import "sync"
+
+// Worker is a job running on the frames of one channel, such as a recorder
+type Worker interface {
+	Stop()
+}
+
+// Workers runs one worker per channel of a hub, each with its own settings of
+// type C
+type Workers[C comparable] struct {
+	start func(*Channel, C) Worker
+
+	mu      sync.Mutex
+	running map[string]*runningWorker[C]
+}
+
+type runningWorker[C comparable] struct {
+	cfg    C
+	ch     *Channel
+	worker Worker
+}
+
+// NewWorkers returns an empty set of workers started with start
+func NewWorkers[C comparable](start func(*Channel, C) Worker) *Workers[C] {
+	return &Workers[C]{start: start, running: make(map[string]*runningWorker[C])}
+}
+
+// Sync runs exactly the workers of configs (channel name -> settings), taking
+// their channels from hub. Workers whose settings or channel changed are
+// restarted.
+func (w *Workers[C]) Sync(hub *Hub, configs map[string]C) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for name, r := range w.running {
+		ch, ok := hub.Channel(name)
+		if cfg, want := configs[name]; !want || !ok || cfg != r.cfg || ch != r.ch {
+			// Stopping may wait for the worker to finish up, which does not hold up the others
+			go r.worker.Stop()
+			delete(w.running, name)
+		}
+	}
+	for name, cfg := range configs {
+		if _, ok := w.running[name]; ok {
+			continue
+		}
+		ch, ok := hub.Channel(name)
+		if !ok {
+			continue
+		}
+		w.running[name] = &runningWorker[C]{cfg: cfg, ch: ch, worker: w.start(ch, cfg)}
+	}
+}
+
+// Running tells whether a worker runs on the channel called name
+func (w *Workers[C]) Running(name string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, ok := w.running[name]
+	return ok
+}
+
+// Close stops every worker and waits for them to finish
+func (w *Workers[C]) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var wg sync.WaitGroup
+	for name, r := range w.running {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.worker.Stop()
+		}()
+		delete(w.running, name)
+	}
+	wg.Wait()
+}
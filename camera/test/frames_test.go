@@ -0,0 +1,77 @@
+package test
+
+import (
+	"bytes"
+	"camera/frames"
+	"camera/stream"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingPublisher struct {
+	mu     sync.Mutex
+	frames []frames.Frame
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, frame frames.Frame) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.frames = append(p.frames, frame)
+	return nil
+}
+
+func (p *recordingPublisher) published() []frames.Frame {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]frames.Frame(nil), p.frames...)
+}
+
+func TestSamplerPublishesAtItsRate(t *testing.T) {
+	cam := newFakeCamera(t)
+	ch := stream.NewChannel("press", &stream.MJPEGSource{URL: cam.server.URL})
+	defer ch.Close()
+	publisher := &recordingPublisher{}
+
+	sampler := frames.StartSampler(ch, frames.Config{
+		Camera:   "press",
+		CameraID: "cam-1",
+		BatchID:  "batch-42",
+		FPS:      10,
+	}, publisher)
+	time.Sleep(time.Second)
+	sampler.Stop()
+
+	published := publisher.published()
+	// The camera sends about 100 frames a second
+	assert.InDelta(t, 10, len(published), 4)
+	for i, frame := range published {
+		assert.Equal(t, int64(i+1), frame.Sequence)
+		assert.Equal(t, "cam-1", frame.CameraID)
+		assert.Equal(t, "batch-42", frame.BatchID)
+		assert.True(t, bytes.HasPrefix(frame.JPEG, []byte{0xFF, 0xD8}))
+	}
+	assert.Eventually(t, func() bool { return cam.open.Load() == 0 }, 2*time.Second, 10*time.Millisecond,
+		"the camera is released when sampling stops")
+}
+
+func TestFrameMessage(t *testing.T) {
+	at := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	msg := frames.Frame{CameraID: "cam-1", Camera: "press", BatchID: "batch-42", Sequence: 7, CapturedAt: at, JPEG: jpegFrame(1)}.Message()
+
+	assert.Equal(t, []byte("cam-1"), msg.Key, "keyed by camera so its frames stay in order")
+	assert.Equal(t, jpegFrame(1), msg.Value)
+	headers := map[string]string{}
+	for _, h := range msg.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	require.Contains(t, headers, "captured_at")
+	assert.Equal(t, "2026-03-04T05:06:07Z", headers["captured_at"])
+	assert.Equal(t, "batch-42", headers["batch_id"])
+	assert.Equal(t, "7", headers["sequence"])
+	assert.Equal(t, "image/jpeg", headers["content_type"])
+}
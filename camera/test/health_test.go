@@ -0,0 +1,48 @@
+package test
+
+import (
+	"camera/health"
+	"camera/models"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthTransitions(t *testing.T) {
+	now := time.Now()
+	down := errors.New("connection refused")
+
+	status, transition := health.Record(models.CameraStatus{}, 120*time.Millisecond, nil, now, 2)
+	assert.Equal(t, models.StatusOnline, status.Status)
+	assert.Equal(t, health.NoChange, transition, "the first check is not an alert")
+	assert.Equal(t, 120, status.LatencyMS)
+	assert.Equal(t, now, status.LastSeen)
+
+	status, transition = health.Record(status, 0, down, now.Add(time.Minute), 2)
+	assert.Equal(t, models.StatusOnline, status.Status, "one failure is tolerated")
+	assert.Equal(t, health.NoChange, transition)
+	assert.Equal(t, "connection refused", status.Error)
+
+	status, transition = health.Record(status, 0, down, now.Add(2*time.Minute), 2)
+	assert.Equal(t, models.StatusOffline, status.Status)
+	assert.Equal(t, health.WentOffline, transition)
+	assert.Equal(t, now, status.LastSeen, "last seen stays at the last frame")
+	assert.Equal(t, now.Add(2*time.Minute), status.ChangedAt)
+
+	status, transition = health.Record(status, 0, down, now.Add(3*time.Minute), 2)
+	assert.Equal(t, health.NoChange, transition, "alerts once per outage")
+
+	status, transition = health.Record(status, 80*time.Millisecond, nil, now.Add(4*time.Minute), 2)
+	assert.Equal(t, models.StatusOnline, status.Status)
+	assert.Equal(t, health.Recovered, transition)
+	assert.Equal(t, 0, status.Failures)
+	assert.Empty(t, status.Error)
+}
+
+func TestHealthNeverOnline(t *testing.T) {
+	status, transition := health.Record(models.CameraStatus{}, 0, errors.New("timeout"), time.Now(), 1)
+	assert.Equal(t, models.StatusOffline, status.Status)
+	assert.Equal(t, health.WentOffline, transition, "a camera that never comes up is reported")
+}
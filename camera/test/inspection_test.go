@@ -0,0 +1,83 @@
+package test
+
+import (
+	"camera/inspection"
+	"camera/models"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShiftsAt(t *testing.T) {
+	loc := time.FixedZone("plant", 2*3600)
+	shifts, err := inspection.ParseShifts(inspection.DefaultShifts, loc)
+	require.NoError(t, err)
+
+	cases := []struct {
+		at    time.Time
+		shift string
+		start time.Time
+	}{
+		{time.Date(2026, 3, 4, 6, 0, 0, 0, loc), "morning", time.Date(2026, 3, 4, 6, 0, 0, 0, loc)},
+		{time.Date(2026, 3, 4, 13, 59, 0, 0, loc), "morning", time.Date(2026, 3, 4, 6, 0, 0, 0, loc)},
+		{time.Date(2026, 3, 4, 14, 0, 0, 0, loc), "evening", time.Date(2026, 3, 4, 14, 0, 0, 0, loc)},
+		{time.Date(2026, 3, 4, 23, 30, 0, 0, loc), "night", time.Date(2026, 3, 4, 22, 0, 0, 0, loc)},
+		// After midnight the night shift is still the one that started the day before
+		{time.Date(2026, 3, 5, 3, 0, 0, 0, loc), "night", time.Date(2026, 3, 4, 22, 0, 0, 0, loc)},
+		// Times are read in the plant's zone whatever zone they arrive in
+		{time.Date(2026, 3, 5, 1, 0, 0, 0, time.UTC), "night", time.Date(2026, 3, 4, 22, 0, 0, 0, loc)},
+	}
+	for _, tc := range cases {
+		shift, start := shifts.At(tc.at)
+		assert.Equal(t, tc.shift, shift, tc.at)
+		assert.True(t, tc.start.Equal(start), "%s: started %s, want %s", tc.at, start, tc.start)
+	}
+
+	partial, err := inspection.ParseShifts("day=08:00-16:00", loc)
+	require.NoError(t, err)
+	shift, _ := partial.At(time.Date(2026, 3, 4, 20, 0, 0, 0, loc))
+	assert.Equal(t, "other", shift)
+
+	_, err = inspection.ParseShifts("day=8-16", loc)
+	assert.Error(t, err)
+}
+
+func TestResultValidation(t *testing.T) {
+	var result inspection.Result
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"camera_id": "cam-1", "batch_id": "b-7", "sequence": 3,
+		"captured_at": "2026-03-04T05:06:07Z",
+		"defects": [{"class": "scratch", "confidence": 0.9, "bbox": [0.1, 0.2, 0.05, 0.05]}]
+	}`), &result))
+	assert.NoError(t, result.Validate())
+	assert.Equal(t, result.DefectID(0), result.DefectID(0), "ids are stable across redeliveries")
+	assert.NotEqual(t, result.DefectID(0), result.DefectID(1))
+
+	result.Defects[0].Confidence = 1.5
+	assert.Error(t, result.Validate())
+	result.Defects[0].Confidence = 0.9
+	result.Defects[0].BBox = []float64{0.1}
+	assert.Error(t, result.Validate())
+	assert.Error(t, (&inspection.Result{CameraID: "cam-1"}).Validate(), "captured_at is required")
+}
+
+func TestDefectFilter(t *testing.T) {
+	d := models.Defect{Line: "press-1", CameraID: "cam-1", Class: "scratch", Confidence: 0.8}
+	cases := []struct {
+		filter models.DefectFilter
+		match  bool
+	}{
+		{models.DefectFilter{}, true},
+		{models.DefectFilter{Line: "press-1", CameraID: "cam-1", Class: "scratch", MinConfidence: 0.8}, true},
+		{models.DefectFilter{Line: "press-2"}, false},
+		{models.DefectFilter{CameraID: "cam-2"}, false},
+		{models.DefectFilter{Class: "dent"}, false},
+		{models.DefectFilter{MinConfidence: 0.81}, false},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.match, tc.filter.Matches(d), "%+v", tc.filter)
+	}
+}
@@ -0,0 +1,115 @@
+package test
+
+import (
+	"camera/ptz"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// onvifCamera answers the ONVIF PTZ calls and checks the WS-Security digest
+func onvifCamera(t *testing.T, password string, bodies chan<- string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var env struct {
+			Token struct {
+				Password string `xml:"Password"`
+				Nonce    string `xml:"Nonce"`
+				Created  string `xml:"Created"`
+			} `xml:"Header>Security>UsernameToken"`
+		}
+		require.NoError(t, xml.Unmarshal(raw, &env))
+		nonce, _ := base64.StdEncoding.DecodeString(env.Token.Nonce)
+		digest := sha1.Sum(append(append(nonce, env.Token.Created...), password...))
+		w.Header().Set("Content-Type", "application/soap+xml")
+		if env.Token.Password != base64.StdEncoding.EncodeToString(digest[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body><s:Fault><s:Reason><s:Text xml:lang="en">Sender not authorized</s:Text></s:Reason></s:Fault></s:Body></s:Envelope>`)
+			return
+		}
+		bodies <- string(raw)
+
+		reply := ""
+		switch {
+		case strings.Contains(string(raw), "<tptz:SetPreset>"):
+			reply = `<tptz:SetPresetResponse><tptz:PresetToken>7</tptz:PresetToken></tptz:SetPresetResponse>`
+		case strings.Contains(string(raw), "<tptz:GetPresets>"):
+			reply = `<tptz:GetPresetsResponse><tptz:Preset token="7"><tt:Name>dock door</tt:Name></tptz:Preset></tptz:GetPresetsResponse>`
+		}
+		io.WriteString(w, `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tptz="http://www.onvif.org/ver20/ptz/wsdl" xmlns:tt="http://www.onvif.org/ver10/schema"><s:Body>`+reply+`</s:Body></s:Envelope>`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestONVIFDriver(t *testing.T) {
+	bodies := make(chan string, 10)
+	server := onvifCamera(t, "secret", bodies)
+	driver, err := ptz.Open("onvif", ptz.Config{URL: server.URL, Username: "operator", Password: "secret", Profile: "Profile_1"})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, driver.ContinuousMove(ctx, ptz.Velocity{Pan: 0.5, Zoom: -0.25}, 1500*time.Millisecond))
+	body := <-bodies
+	assert.Contains(t, body, `<tptz:ProfileToken>Profile_1</tptz:ProfileToken>`)
+	assert.Contains(t, body, `<tt:PanTilt x="0.5" y="0"/><tt:Zoom x="-0.25"/>`)
+	assert.Contains(t, body, `<tptz:Timeout>PT1.5S</tptz:Timeout>`)
+
+	token, err := driver.SetPreset(ctx, "dock <door>")
+	require.NoError(t, err)
+	assert.Equal(t, "7", token)
+	assert.Contains(t, <-bodies, `<tptz:PresetName>dock &lt;door&gt;</tptz:PresetName>`)
+
+	presets, err := driver.Presets(ctx)
+	require.NoError(t, err)
+	<-bodies
+	assert.Equal(t, []ptz.Preset{{Token: "7", Name: "dock door"}}, presets)
+
+	wrong, err := ptz.Open("onvif", ptz.Config{URL: server.URL, Username: "operator", Password: "guess", Profile: "Profile_1"})
+	require.NoError(t, err)
+	err = wrong.Stop(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Sender not authorized")
+}
+
+func TestFakeDriver(t *testing.T) {
+	// Registering twice, as every test run does, is harmless
+	ptz.RegisterFake()
+	ptz.RegisterFake()
+	assert.Contains(t, ptz.Drivers(), "fake")
+	assert.Contains(t, ptz.Drivers(), "onvif")
+	_, err := ptz.Open("pelco", ptz.Config{})
+	assert.Error(t, err)
+
+	driver, err := ptz.Open("fake", ptz.Config{URL: "fake://yard"})
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, driver.ContinuousMove(ctx, ptz.Velocity{Tilt: 1}, time.Second))
+	token, err := driver.SetPreset(ctx, "gate")
+	require.NoError(t, err)
+	require.NoError(t, driver.GotoPreset(ctx, token))
+	assert.Error(t, driver.GotoPreset(ctx, "missing"))
+
+	assert.Equal(t, []string{"move 0 1 0 1s", "set gate", "goto " + token}, ptz.FakeCamera("fake://yard").Commands(),
+		"cameras with the same URL share one fake")
+
+	dock, err := ptz.Open("fake", ptz.Config{URL: "fake://dock"})
+	require.NoError(t, err)
+	require.NoError(t, dock.ContinuousMove(ctx, ptz.Velocity{Pan: 1}, time.Second))
+	for i := 0; i < 150; i++ {
+		require.NoError(t, dock.Stop(ctx))
+	}
+	commands := ptz.FakeCamera("fake://dock").Commands()
+	assert.Len(t, commands, 100, "only the latest commands are kept")
+	assert.NotContains(t, commands, "move 1 0 0 1s")
+}
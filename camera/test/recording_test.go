@@ -0,0 +1,166 @@
+package test
+
+import (
+	"bytes"
+	"camera/models"
+	"camera/recording"
+	"camera/stream"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	store := &recording.LocalStore{Dir: t.TempDir()}
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "cam/2026-01-02/1.mjpeg", strings.NewReader("clip"), 4))
+	body, size, err := store.Open(ctx, "cam/2026-01-02/1.mjpeg")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, "clip", string(data))
+	assert.Equal(t, int64(4), size)
+
+	require.NoError(t, store.Delete(ctx, "cam/2026-01-02/1.mjpeg"))
+	_, _, err = store.Open(ctx, "cam/2026-01-02/1.mjpeg")
+	assert.ErrorIs(t, err, recording.ErrNotFound)
+	assert.NoError(t, store.Delete(ctx, "cam/2026-01-02/1.mjpeg"), "deleting twice is fine")
+	assert.Error(t, store.Put(ctx, "../escape", strings.NewReader("x"), 1))
+}
+
+func TestS3Store(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+			http.Error(w, "unsigned", http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.Path], _ = io.ReadAll(r.Body)
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				http.Error(w, "NoSuchKey", http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	store := &recording.S3Store{Endpoint: server.URL, Bucket: "clips", AccessKey: "access", SecretKey: "secret"}
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, "cam/1.mjpeg", strings.NewReader("clip"), 4))
+	assert.Contains(t, objects, "/clips/cam/1.mjpeg", "path style addressing")
+
+	body, size, err := store.Open(ctx, "cam/1.mjpeg")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, "clip", string(data))
+	assert.Equal(t, int64(4), size)
+
+	require.NoError(t, store.Delete(ctx, "cam/1.mjpeg"))
+	_, _, err = store.Open(ctx, "cam/1.mjpeg")
+	assert.ErrorIs(t, err, recording.ErrNotFound)
+
+	store.AccessKey = "wrong"
+	assert.Error(t, store.Put(ctx, "cam/2.mjpeg", strings.NewReader("clip"), 4))
+}
+
+// solidJPEG encodes a 320x240 image whose left half is left and right half right
+func solidJPEG(t *testing.T, left, right uint8) []byte {
+	img := image.NewGray(image.Rect(0, 0, 320, 240))
+	for y := 0; y < 240; y++ {
+		for x := 0; x < 320; x++ {
+			v := left
+			if x >= 160 {
+				v = right
+			}
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	return buf.Bytes()
+}
+
+func TestMotionDetector(t *testing.T) {
+	detector := &recording.MotionDetector{Threshold: 0.02}
+	still := solidJPEG(t, 80, 80)
+
+	moving, err := detector.Detect(still)
+	require.NoError(t, err)
+	assert.False(t, moving, "the first frame has nothing to compare with")
+	moving, _ = detector.Detect(still)
+	assert.False(t, moving)
+	moving, _ = detector.Detect(solidJPEG(t, 80, 220))
+	assert.True(t, moving)
+
+	_, err = detector.Detect([]byte("not a jpeg"))
+	assert.Error(t, err)
+}
+
+func TestContinuousRecording(t *testing.T) {
+	cam := newFakeCamera(t)
+	ch := stream.NewChannel("dock", &stream.MJPEGSource{URL: cam.server.URL})
+	defer ch.Close()
+	store := &recording.LocalStore{Dir: t.TempDir()}
+
+	var mu sync.Mutex
+	var clips []recording.Clip
+	recorder := recording.Start(ch, recording.Config{
+		Camera:   "dock",
+		CameraID: "00000000-0000-0000-0000-000000000001",
+		Mode:     models.RecordingContinuous,
+		FPS:      50,
+		Segment:  200 * time.Millisecond,
+	}, store, func(clip recording.Clip) {
+		mu.Lock()
+		defer mu.Unlock()
+		clips = append(clips, clip)
+	})
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(clips) >= 2
+	}, 3*time.Second, 20*time.Millisecond)
+	recorder.Stop()
+	assert.Eventually(t, func() bool { return cam.open.Load() == 0 }, 2*time.Second, 10*time.Millisecond,
+		"the camera is released when recording stops")
+
+	mu.Lock()
+	defer mu.Unlock()
+	clip := clips[0]
+	assert.Equal(t, models.RecordingContinuous, clip.Trigger)
+	assert.True(t, strings.HasPrefix(clip.Key, "00000000-0000-0000-0000-000000000001/"+clip.Start.UTC().Format(time.DateOnly)+"/"))
+	assert.False(t, clip.End.Before(clip.Start))
+	assert.Less(t, clip.End.Sub(clip.Start), time.Second)
+
+	body, size, err := store.Open(context.Background(), clip.Key)
+	require.NoError(t, err)
+	defer body.Close()
+	assert.Equal(t, clip.Size, size)
+	frames := 0
+	stream.SplitJPEG(body, func([]byte) { frames++ })
+	assert.Equal(t, clip.Frames, frames, "a clip is the concatenated frames")
+}
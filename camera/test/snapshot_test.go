@@ -0,0 +1,69 @@
+package test
+
+import (
+	"bytes"
+	"camera/stream"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotConnectsOnDemand(t *testing.T) {
+	cam := newFakeCamera(t)
+	ch := stream.NewChannel("dock", &stream.MJPEGSource{URL: cam.server.URL})
+	defer ch.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	frame, err := ch.Snapshot(ctx)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(frame, []byte{0xFF, 0xD8}))
+	assert.Equal(t, 0, ch.Viewers())
+	assert.Eventually(t, func() bool { return cam.open.Load() == 0 }, 2*time.Second, 10*time.Millisecond,
+		"the camera is released after the snapshot")
+}
+
+func TestThumbnail(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 640, 480))
+	for y := 0; y < 480; y++ {
+		for x := 0; x < 640; x++ {
+			src.Set(x, y, color.RGBA{R: 200, G: 40, B: 40, A: 0xFF})
+		}
+	}
+	var frame bytes.Buffer
+	require.NoError(t, jpeg.Encode(&frame, src, nil))
+
+	thumb, err := stream.Thumbnail(frame.Bytes(), 320)
+	require.NoError(t, err)
+	img, err := jpeg.Decode(bytes.NewReader(thumb))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 320, 240), img.Bounds())
+	r, _, _, _ := img.At(160, 120).RGBA()
+	assert.InDelta(t, 200, r>>8, 10)
+
+	_, err = stream.Thumbnail([]byte("not a jpeg"), 320)
+	assert.Error(t, err)
+}
+
+func TestProbeReportsUpstreamError(t *testing.T) {
+	cam := newFakeCamera(t)
+	ch := stream.NewChannel("dock", &stream.MJPEGSource{URL: cam.server.URL})
+	defer ch.Close()
+	latency, err := ch.Probe(context.Background())
+	require.NoError(t, err)
+	assert.Greater(t, latency, time.Duration(0))
+
+	dead := stream.NewChannel("gate", &stream.MJPEGSource{URL: "http://127.0.0.1:1/mjpeg"})
+	defer dead.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	_, err = dead.Probe(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, dead.Err(), "connection refused")
+}
@@ -0,0 +1,241 @@
+package test
+
+import (
+	"bytes"
+	"camera/routes"
+	"camera/stream"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCamera serves an endless MJPEG stream like an IP camera and counts how
+// many connections are open
+type fakeCamera struct {
+	server      *httptest.Server
+	connections atomic.Int32
+	open        atomic.Int32
+}
+
+func newFakeCamera(t *testing.T) *fakeCamera {
+	return startFakeCamera(t, true)
+}
+
+// startFakeCamera starts a fake camera that sends a Content-Length with each
+// frame when withLength is set, and only boundaries otherwise
+func startFakeCamera(t *testing.T, withLength bool) *fakeCamera {
+	cam := &fakeCamera{}
+	cam.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cam.connections.Add(1)
+		cam.open.Add(1)
+		defer cam.open.Add(-1)
+
+		w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary=--cam")
+		for i := 0; ; i++ {
+			frame := jpegFrame(i)
+			length := ""
+			if withLength {
+				length = fmt.Sprintf("Content-Length: %d\r\n", len(frame))
+			}
+			_, err := fmt.Fprintf(w, "--cam\r\nContent-Type: image/jpeg\r\n%s\r\n%s\r\n", length, frame)
+			if err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}))
+	t.Cleanup(cam.server.Close)
+	return cam
+}
+
+// jpegFrame is a stand-in JPEG: SOI marker, a payload and EOI marker
+func jpegFrame(i int) []byte {
+	return append(append([]byte{0xFF, 0xD8}, fmt.Sprintf("frame-%d", i)...), 0xFF, 0xD9)
+}
+
+func receive(t *testing.T, frames <-chan []byte) []byte {
+	select {
+	case frame := <-frames:
+		return frame
+	case <-time.After(2 * time.Second):
+		t.Fatal("no frame received")
+		return nil
+	}
+}
+
+func TestViewersShareOneUpstream(t *testing.T) {
+	cam := newFakeCamera(t)
+	ch := stream.NewChannel("channel1", &stream.MJPEGSource{URL: cam.server.URL})
+	defer ch.Close()
+
+	first, leaveFirst := ch.Subscribe()
+	second, leaveSecond := ch.Subscribe()
+	assert.True(t, bytes.HasPrefix(receive(t, first), []byte{0xFF, 0xD8}))
+	assert.True(t, bytes.HasPrefix(receive(t, second), []byte{0xFF, 0xD8}))
+	assert.Equal(t, int32(1), cam.connections.Load())
+	assert.Equal(t, 2, ch.Viewers())
+
+	leaveFirst()
+	receive(t, second)
+	leaveSecond()
+	assert.Eventually(t, func() bool { return cam.open.Load() == 0 }, 2*time.Second, 10*time.Millisecond,
+		"upstream closes with the last viewer")
+
+	third, leaveThird := ch.Subscribe()
+	defer leaveThird()
+	receive(t, third)
+	assert.Equal(t, int32(2), cam.connections.Load())
+}
+
+func TestChannelReconnects(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			http.Error(w, "camera rebooting", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary=cam")
+		frame := jpegFrame(1)
+		fmt.Fprintf(w, "--cam\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n%s\r\n", len(frame), frame)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ch := stream.NewChannel("channel1", &stream.MJPEGSource{URL: server.URL})
+	defer ch.Close()
+	frames, leave := ch.Subscribe()
+	defer leave()
+	assert.Equal(t, jpegFrame(1), receive(t, frames))
+}
+
+func TestSplitJPEG(t *testing.T) {
+	raw := append([]byte("garbage"), jpegFrame(1)...)
+	raw = append(raw, 0x00)
+	raw = append(raw, jpegFrame(2)...)
+
+	var frames [][]byte
+	err := stream.SplitJPEG(bytes.NewReader(raw), func(frame []byte) { frames = append(frames, frame) })
+	assert.ErrorContains(t, err, "EOF")
+	assert.Equal(t, [][]byte{jpegFrame(1), jpegFrame(2)}, frames)
+}
+
+func TestNewSource(t *testing.T) {
+	source, err := stream.NewSource("rtsp://10.0.0.5/stream1")
+	require.NoError(t, err)
+	assert.IsType(t, &stream.RTSPSource{}, source)
+
+	source, err = stream.NewSource("http://10.0.0.6/mjpeg")
+	require.NoError(t, err)
+	assert.IsType(t, &stream.MJPEGSource{}, source)
+
+	_, err = stream.NewSource("ftp://10.0.0.7/video")
+	assert.Error(t, err)
+}
+
+func TestSourcesFromEnv(t *testing.T) {
+	t.Setenv("CAMERA_SOURCES", "channel1=rtsp://10.0.0.5/stream1, channel2=http://10.0.0.6/mjpeg?a=b")
+	sources, err := stream.SourcesFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"channel1": "rtsp://10.0.0.5/stream1",
+		"channel2": "http://10.0.0.6/mjpeg?a=b",
+	}, sources)
+
+	t.Setenv("CAMERA_SOURCES", "channel1")
+	_, err = stream.SourcesFromEnv()
+	assert.Error(t, err)
+}
+
+func TestStreamMJPEGRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cam := newFakeCamera(t)
+	hub, err := stream.NewHub(map[string]string{"channel1": cam.server.URL})
+	require.NoError(t, err)
+	defer hub.Close()
+	routes.Streams = hub
+
+	router := gin.New()
+	router.GET("/stream/:channel", routes.StreamMJPEG)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/stream/channel9")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/stream/channel1", nil)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/x-mixed-replace", mediaType)
+	parts := multipart.NewReader(resp.Body, params["boundary"])
+	for i := 0; i < 3; i++ {
+		part, err := parts.NextPart()
+		require.NoError(t, err)
+		assert.Equal(t, "image/jpeg", part.Header.Get("Content-Type"))
+	}
+}
+
+// Viewers share each frame; without a Content-Length the frames have spare
+// capacity, so a viewer that appended to one would race with the others
+func TestStreamMJPEGRouteSharesFrames(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cam := startFakeCamera(t, false)
+	hub, err := stream.NewHub(map[string]string{"channel1": cam.server.URL})
+	require.NoError(t, err)
+	defer hub.Close()
+	routes.Streams = hub
+
+	router := gin.New()
+	router.GET("/stream/:channel", routes.StreamMJPEG)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var viewers []*multipart.Reader
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/stream/channel1", nil)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		viewers = append(viewers, multipart.NewReader(resp.Body, params["boundary"]))
+	}
+
+	for i := 0; i < 5; i++ {
+		for _, parts := range viewers {
+			part, err := parts.NextPart()
+			require.NoError(t, err)
+			frame, err := io.ReadAll(part)
+			require.NoError(t, err)
+			assert.True(t, bytes.HasPrefix(frame, []byte{0xFF, 0xD8}))
+			assert.True(t, bytes.HasSuffix(frame, []byte{0xFF, 0xD9}))
+		}
+	}
+	assert.Equal(t, int32(1), cam.connections.Load())
+}
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"camera/stream"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubWorker records when it is stopped
+type stubWorker struct {
+	stopped chan struct{}
+}
+
+func (w *stubWorker) Stop() { close(w.stopped) }
+
+func stoppedSoon(w *stubWorker) bool {
+	select {
+	case <-w.stopped:
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func TestWorkersFollowConfigsAndChannels(t *testing.T) {
+	cam := newFakeCamera(t)
+	hub, err := stream.NewHub(map[string]string{"channel1": cam.server.URL, "channel2": cam.server.URL})
+	require.NoError(t, err)
+	defer hub.Close()
+
+	var mu sync.Mutex
+	started := map[string][]*stubWorker{}
+	workers := stream.NewWorkers(func(ch *stream.Channel, fps int) stream.Worker {
+		w := &stubWorker{stopped: make(chan struct{})}
+		mu.Lock()
+		started[ch.Name] = append(started[ch.Name], w)
+		mu.Unlock()
+		return w
+	})
+	latest := func(name string) *stubWorker {
+		mu.Lock()
+		defer mu.Unlock()
+		return started[name][len(started[name])-1]
+	}
+
+	// Cameras missing from the hub get no worker
+	workers.Sync(hub, map[string]int{"channel1": 1, "channel2": 1, "channel9": 1})
+	assert.True(t, workers.Running("channel1"))
+	assert.True(t, workers.Running("channel2"))
+	assert.False(t, workers.Running("channel9"))
+
+	// Same settings: nothing restarts
+	first := latest("channel1")
+	workers.Sync(hub, map[string]int{"channel1": 1, "channel2": 1})
+	assert.Same(t, first, latest("channel1"))
+
+	// New settings restart the worker, a dropped camera stops it
+	second := latest("channel2")
+	workers.Sync(hub, map[string]int{"channel1": 2})
+	assert.True(t, stoppedSoon(first))
+	assert.True(t, stoppedSoon(second))
+	assert.NotSame(t, first, latest("channel1"))
+	assert.False(t, workers.Running("channel2"))
+
+	// A channel that points somewhere else is a new channel
+	other := newFakeCamera(t)
+	require.NoError(t, hub.Sync(map[string]string{"channel1": other.server.URL}))
+	restarted := latest("channel1")
+	workers.Sync(hub, map[string]int{"channel1": 2})
+	assert.True(t, stoppedSoon(restarted))
+	assert.NotSame(t, restarted, latest("channel1"))
+
+	last := latest("channel1")
+	workers.Close()
+	select {
+	case <-last.stopped:
+	default:
+		t.Fatal("Close returned before stopping the workers")
+	}
+	assert.False(t, workers.Running("channel1"))
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"camera/models"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// SourceURL returns the URL to open for a camera, with the credentials its
+// CredentialsRef points to. CAMERA_CREDENTIALS_<REF> holds "user:password";
+// only variables with that prefix can be referenced.
+func SourceURL(cam models.Camera) (string, error) {
+	if cam.CredentialsRef == "" {
+		return cam.SourceURL, nil
+	}
+
+	user, password, err := Credentials(cam)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(cam.SourceURL)
+	if err != nil {
+		return "", err
+	}
+	u.User = url.UserPassword(user, password)
+	return u.String(), nil
+}
+
+// Credentials returns the user and password CredentialsRef points to, empty
+// when the camera has none
+func Credentials(cam models.Camera) (string, string, error) {
+	if cam.CredentialsRef == "" {
+		return "", "", nil
+	}
+	key := "CAMERA_CREDENTIALS_" + strings.ToUpper(cam.CredentialsRef)
+	creds, ok := os.LookupEnv(key)
+	if !ok {
+		return "", "", fmt.Errorf("%s is not set", key)
+	}
+	user, password, _ := strings.Cut(creds, ":")
+	return user, password, nil
+}
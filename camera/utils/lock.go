@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/redis/go-redis/v9"
+)
+
+// ClaimRun makes a periodic task run on only one instance per interval. task
+// names the job, e.g. "thumb:<camera>".
+func ClaimRun(task string, interval time.Duration) bool {
+	ok, err := RDB.SetNX(Ctx, "camera:lock:"+task, 1, interval*9/10).Result()
+	return err == nil && ok
+}
+
+// InstanceID tells the running instances apart when they hold leases
+var InstanceID = gocql.TimeUUID().String()
+
+// claimHolder takes a lock that is free or already held by ARGV[1], (re)sets
+// its ttl and returns the holder
+var claimHolder = redis.NewScript(`
+local holder = redis.call("GET", KEYS[1])
+if holder == false or holder == ARGV[1] then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return ARGV[1]
+end
+return holder`)
+
+// releaseHolder drops a lock held by ARGV[1]
+var releaseHolder = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// ClaimLease makes a long running task, e.g. "record:<camera>", run on only one
+// instance. The instance holding the lease keeps it by claiming it again
+// before ttl runs out.
+func ClaimLease(task string, ttl time.Duration) (bool, error) {
+	holder, err := claimHolder.Run(Ctx, RDB, []string{"camera:lease:" + task}, InstanceID, ttl.Milliseconds()).Text()
+	return holder == InstanceID, err
+}
+
+// ClaimControl gives owner (an operator) control of a camera for ttl, unless
+// someone else holds it. It returns the holder and how long their control lasts.
+func ClaimControl(cameraID, owner string, ttl time.Duration) (string, time.Duration, error) {
+	key := "camera:control:" + cameraID
+	holder, err := claimHolder.Run(Ctx, RDB, []string{key}, owner, ttl.Milliseconds()).Text()
+	if err != nil || holder == owner {
+		return holder, ttl, err
+	}
+	left, err := RDB.PTTL(Ctx, key).Result()
+	return holder, left, err
+}
+
+// ReleaseControl gives up owner's control of a camera
+func ReleaseControl(cameraID, owner string) error {
+	return releaseHolder.Run(Ctx, RDB, []string{"camera:control:" + cameraID}, owner).Err()
+}
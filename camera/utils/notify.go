@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Notification is an alert pushed to connected users by the Feedback service.
+// It goes to TargetID, or to everyone whose token grants Permission.
+type Notification struct {
+	TargetID   string `json:"target_id,omitempty"`
+	Permission string `json:"permission,omitempty"`
+	Title      string `json:"title"`
+	Type       string `json:"type"` // info, error
+	Content    string `json:"content"`
+}
+
+var notifyClient = &http.Client{Timeout: 5 * time.Second}
+
+// Notify posts n to the Feedback service (FEEDBACK_URL, default
+// http://localhost:8081), authenticated with INTERNAL_API_TOKEN when set
+func Notify(n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	baseURL := os.Getenv("FEEDBACK_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8081"
+	}
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/internal/notify", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token := os.Getenv("INTERNAL_API_TOKEN"); token != "" {
+		req.Header.Set("X-Internal-Token", token)
+	}
+
+	resp, err := notifyClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("feedback service returned %s", resp.Status)
+	}
+	return nil
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
+	"shared/store"
     "time"
 	"github.com/redis/go-redis/v9"
 )
@@ -15,19 +15,9 @@ var (
 )
 
 func ConnectRedis() {
-	addr := os.Getenv("REDIS_HOST")
-	if addr == "" {
-		addr = "localhost:6379"
-	}
-	password := os.Getenv("REDIS_PASSWORD")
-
-	RDB = redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: password,
-		DB:       0,
-	})
-
-	if err := RDB.Ping(Ctx).Err(); err != nil {
+	var err error
+	RDB, err = store.ConnectRedis(Ctx, store.RedisOptionsFromEnv())
+	if err != nil {
 		log.Fatalf("❌ Failed to connect to Redis: %v", err)
 	}
 
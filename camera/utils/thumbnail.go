@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SaveThumbnail caches the latest thumbnail of a camera in Redis for ttl
+func SaveThumbnail(name string, jpeg []byte, at time.Time, ttl time.Duration) error {
+	key := "camera:thumb:" + name
+	_, err := RDB.TxPipelined(Ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(Ctx, key, "jpeg", jpeg, "at", at.Unix())
+		pipe.Expire(Ctx, key, ttl)
+		return nil
+	})
+	return err
+}
+
+// Thumbnail returns the cached thumbnail of a camera and when it was taken,
+// or redis.Nil when there is none
+func Thumbnail(name string) ([]byte, time.Time, error) {
+	values, err := RDB.HGetAll(Ctx, "camera:thumb:"+name).Result()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	jpeg, ok := values["jpeg"]
+	if !ok {
+		return nil, time.Time{}, redis.Nil
+	}
+	at, _ := strconv.ParseInt(values["at"], 10, 64)
+	return []byte(jpeg), time.Unix(at, 0), nil
+}
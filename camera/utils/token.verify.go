@@ -1,17 +1,22 @@
 package utils
+
 import (
-	"os"
-	"github.com/golang-jwt/jwt/v5"
+	"shared/auth"
 )
-func ParseToken(tokenStr string) (jwt.MapClaims, error) {
-	secret := os.Getenv("JWT_SECRET")
 
-	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+// Verifier checks access tokens with the public keys published by Auth
+var Verifier *auth.Verifier
+
+// InitVerifier points Verifier at JWKS_URL and only accepts tokens issued for
+// the "camera" audience. Revoked tokens are looked up in Redis, so call it after
+// the environment is loaded and Redis is connected.
+func InitVerifier() {
+	opts := auth.VerifierOptionsFromEnv("camera")
+	opts.Revocations = auth.NewRevocations(RDB)
+	Verifier = auth.NewVerifier(auth.NewJWKS(auth.JWKSURLFromEnv(), auth.JWKSOptions{}), opts)
+}
 
-	if err != nil || !token.Valid {
-		return nil, err
-	}
-	return token.Claims.(jwt.MapClaims), nil
+// ParseToken verifies an access token. Errors are *auth.TokenError.
+func ParseToken(tokenStr string) (*auth.Claims, error) {
+	return Verifier.Verify(tokenStr)
 }
@@ -0,0 +1,48 @@
+// Package auth verifies the access tokens issued by the Auth service and
+// authorizes requests from the permissions they carry.
+package auth
+
+import (
+	"errors"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidClaims is returned for tokens missing an application claim
+var ErrInvalidClaims = errors.New("missing or inconsistent claims")
+
+// Claims is the payload of an access token. The registered claims carry the
+// issuer (iss), intended services (aud), user id (sub), token id (jti) and the
+// iat/nbf/exp validity window.
+type Claims struct {
+	UserID      string   `json:"user_id"`
+	Role        string   `json:"role"`
+	Email       string   `json:"email"`
+	Name        string   `json:"name,omitempty"`
+	SessionID   string   `json:"sid,omitempty"`
+	MFA         bool     `json:"mfa"`
+	Permissions []string `json:"perms,omitempty"`
+	// Purpose is only set on single-purpose tokens (email verification, ...),
+	// which are never accepted as access tokens
+	Purpose string `json:"purpose,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Validate is called by the jwt parser after the registered claims passed
+func (c *Claims) Validate() error {
+	if c.Purpose != "" {
+		return ErrWrongTokenPurpose
+	}
+	if c.UserID == "" || c.Role == "" || c.Email == "" || c.ID == "" {
+		return ErrInvalidClaims
+	}
+	if c.Subject != "" && c.Subject != c.UserID {
+		return ErrInvalidClaims
+	}
+	return nil
+}
+
+// Can reports whether the token grants permission
+func (c *Claims) Can(permission string) bool {
+	return HasPermission(c.Permissions, permission)
+}
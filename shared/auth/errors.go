@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Reason is a stable, machine readable cause of a rejected token. Services
+// return it to clients as "reason" next to the human readable error.
+type Reason string
+
+const (
+	ReasonMissing         Reason = "missing_token"
+	ReasonMalformed       Reason = "malformed_token"
+	ReasonBadSignature    Reason = "invalid_signature"
+	ReasonUnknownKey      Reason = "unknown_key"
+	ReasonExpired         Reason = "token_expired"
+	ReasonNotYetValid     Reason = "token_not_yet_valid"
+	ReasonInvalidIssuer   Reason = "invalid_issuer"
+	ReasonInvalidAudience Reason = "invalid_audience"
+	ReasonWrongPurpose    Reason = "wrong_token_purpose"
+	ReasonInvalidClaims   Reason = "invalid_claims"
+	ReasonRevoked         Reason = "token_revoked"
+	// ReasonUnavailable means the revocation list could not be checked
+	ReasonUnavailable Reason = "verification_unavailable"
+)
+
+// messages are the human readable texts of each reason
+var messages = map[Reason]string{
+	ReasonMissing:         "Missing token",
+	ReasonMalformed:       "Malformed token",
+	ReasonBadSignature:    "Invalid token signature",
+	ReasonUnknownKey:      "Token signed with an unknown key",
+	ReasonExpired:         "Token expired",
+	ReasonNotYetValid:     "Token not valid yet",
+	ReasonInvalidIssuer:   "Token issued by an untrusted issuer",
+	ReasonInvalidAudience: "Token not intended for this service",
+	ReasonWrongPurpose:    "Token not valid for this purpose",
+	ReasonInvalidClaims:   "Invalid token claims",
+	ReasonRevoked:         "Token revoked",
+	ReasonUnavailable:     "Token could not be verified right now",
+}
+
+// ErrMissingToken is returned when a request carries no token at all
+var ErrMissingToken = errors.New("missing token")
+
+// TokenError is the error returned by Verifier.Verify
+type TokenError struct {
+	Reason Reason
+	Err    error
+}
+
+func (e *TokenError) Error() string {
+	return string(e.Reason) + ": " + e.Err.Error()
+}
+
+func (e *TokenError) Unwrap() error {
+	return e.Err
+}
+
+// Message returns the human readable text of the reason
+func (e *TokenError) Message() string {
+	return messages[e.Reason]
+}
+
+// ReasonOf returns the reason of a verification error; errors that are not
+// TokenErrors map to ReasonMalformed
+func ReasonOf(err error) Reason {
+	var tokenErr *TokenError
+	if errors.As(err, &tokenErr) {
+		return tokenErr.Reason
+	}
+	return ReasonMalformed
+}
+
+// classify wraps an error of the jwt parser in a TokenError
+func classify(err error) *TokenError {
+	reason := ReasonMalformed
+	switch {
+	case errors.Is(err, ErrMissingToken):
+		reason = ReasonMissing
+	case errors.Is(err, ErrUnknownKey):
+		reason = ReasonUnknownKey
+	case errors.Is(err, ErrTokenRevoked):
+		reason = ReasonRevoked
+	case errors.Is(err, ErrWrongTokenPurpose):
+		reason = ReasonWrongPurpose
+	case errors.Is(err, jwt.ErrTokenExpired):
+		reason = ReasonExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		reason = ReasonNotYetValid
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		reason = ReasonInvalidIssuer
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		reason = ReasonInvalidAudience
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		reason = ReasonBadSignature
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing), errors.Is(err, jwt.ErrTokenInvalidClaims), errors.Is(err, ErrInvalidClaims):
+		reason = ReasonInvalidClaims
+	}
+	return &TokenError{Reason: reason, Err: err}
+}
+
+// Unauthorized aborts c with a 401 describing err (503 when the revocation
+// list is unreachable)
+func Unauthorized(c *gin.Context, err error) {
+	reason := ReasonOf(err)
+	c.AbortWithStatusJSON(statusOf(reason), gin.H{"error": messages[reason], "reason": reason})
+}
+
+// WriteUnauthorized is Unauthorized for a plain net/http response
+func WriteUnauthorized(w http.ResponseWriter, err error) {
+	reason := ReasonOf(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusOf(reason))
+	w.Write([]byte(`{"error":"` + messages[reason] + `","reason":"` + string(reason) + `"}`))
+}
+
+func statusOf(reason Reason) int {
+	if reason == ReasonUnavailable {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusUnauthorized
+}
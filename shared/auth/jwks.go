@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultJWKSURL is where the Auth service publishes its public keys
+const DefaultJWKSURL = "http://localhost:8080/.well-known/jwks.json"
+
+// KeySource resolves the key a token was signed with from its kid
+type KeySource interface {
+	// Key returns the algorithm and public key of kid, or ErrUnknownKey
+	Key(kid string) (alg string, key interface{}, err error)
+}
+
+// JWKSOptions tunes a JWKS key source. Zero values pick the defaults.
+type JWKSOptions struct {
+	HTTPClient *http.Client
+	// TTL is how long fetched keys are used before asking again (default 10m)
+	TTL time.Duration
+	// MinRefresh stops tokens with random kids from hammering Auth (default 30s)
+	MinRefresh time.Duration
+}
+
+// JWKS is a KeySource backed by a remote JSON Web Key Set. Unknown kids trigger
+// a refresh, so keys rotated in by Auth are picked up right away.
+type JWKS struct {
+	url  string
+	opts JWKSOptions
+
+	mu          sync.Mutex
+	keys        map[string]jwk
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	refreshing  chan struct{} // closed when the fetch in flight ends
+	fetchErr    error         // of the last fetch
+}
+
+type jwk struct {
+	alg string
+	key interface{}
+}
+
+// NewJWKS returns a key source for the set published at url
+func NewJWKS(url string, opts JWKSOptions) *JWKS {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 5 * time.Second}
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 10 * time.Minute
+	}
+	if opts.MinRefresh <= 0 {
+		opts.MinRefresh = 30 * time.Second
+	}
+	return &JWKS{url: url, opts: opts}
+}
+
+// JWKSURLFromEnv returns JWKS_URL, or DefaultJWKSURL when unset
+func JWKSURLFromEnv() string {
+	if url := os.Getenv("JWKS_URL"); url != "" {
+		return url
+	}
+	return DefaultJWKSURL
+}
+
+// Key implements KeySource. The set is fetched without holding the lock, so
+// lookups of known keys never wait on Auth.
+func (s *JWKS) Key(kid string) (string, interface{}, error) {
+	s.mu.Lock()
+	key, ok := s.keys[kid]
+	var done chan struct{}
+	if !ok || time.Since(s.fetchedAt) > s.opts.TTL {
+		done = s.refresh()
+	}
+	s.mu.Unlock()
+
+	// A stale key stays in use while the refresh runs, an unknown one waits for it
+	if !ok && done != nil {
+		<-done
+		s.mu.Lock()
+		key, ok = s.keys[kid]
+		err := s.fetchErr
+		s.mu.Unlock()
+		if !ok && err != nil {
+			return "", nil, err
+		}
+	}
+	if !ok {
+		return "", nil, ErrUnknownKey
+	}
+	return key.alg, key.key, nil
+}
+
+// refresh starts fetching the set unless a fetch is in flight or the last one
+// began less than MinRefresh ago. It returns a channel closed when the fetch
+// in flight ends, or nil when there is none. s.mu must be held.
+func (s *JWKS) refresh() chan struct{} {
+	if s.refreshing == nil && time.Since(s.attemptedAt) >= s.opts.MinRefresh {
+		s.attemptedAt = time.Now()
+		done := make(chan struct{})
+		s.refreshing = done
+		go func() {
+			keys, err := s.fetch()
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			// While Auth is unreachable the keys we already have stay in use
+			if err == nil {
+				s.keys = keys
+				s.fetchedAt = time.Now()
+			}
+			s.fetchErr = err
+			s.refreshing = nil
+			close(done)
+		}()
+	}
+	return s.refreshing
+}
+
+func (s *JWKS) fetch() (map[string]jwk, error) {
+	resp, err := s.opts.HTTPClient.Get(s.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Alg string `json:"alg"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]jwk)
+	for _, k := range set.Keys {
+		switch {
+		case k.Kty == "RSA" && k.Alg == "RS256":
+			n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+			e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = jwk{alg: k.Alg, key: &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}}
+		case k.Kty == "OKP" && k.Crv == "Ed25519" && k.Alg == "EdDSA":
+			x, err := base64.RawURLEncoding.DecodeString(k.X)
+			if err != nil || len(x) != ed25519.PublicKeySize {
+				continue
+			}
+			keys[k.Kid] = jwk{alg: k.Alg, key: ed25519.PublicKey(x)}
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKey is where services store the verified *Claims on a gin context
+const ContextKey = "claims"
+
+// ClaimsFrom returns the claims stored on c, or nil
+func ClaimsFrom(c *gin.Context) *Claims {
+	v, ok := c.Get(ContextKey)
+	if !ok {
+		return nil
+	}
+	claims, _ := v.(*Claims)
+	return claims
+}
+
+// RequirePermission lets the request through only when the verified token
+// grants permission. It must run after the middleware that stores the claims.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := ClaimsFrom(c)
+		if claims == nil || !claims.Can(permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied", "permission": permission})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
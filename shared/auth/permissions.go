@@ -0,0 +1,46 @@
+package auth
+
+import "strings"
+
+// Permissions are colon separated names such as "camera:view:channel2" or
+// "chat:moderate". In a granted permission a "*" segment matches any single
+// segment, and a trailing "*" matches everything below it, so "camera:*" grants
+// every camera permission and "*" grants everything.
+
+// PermissionMatches reports whether granted covers required
+func PermissionMatches(granted, required string) bool {
+	g := strings.Split(granted, ":")
+	r := strings.Split(required, ":")
+	for i, segment := range g {
+		if segment == "*" && i == len(g)-1 {
+			return len(r) >= len(g)
+		}
+		if i >= len(r) || (segment != "*" && segment != r[i]) {
+			return false
+		}
+	}
+	return len(g) == len(r)
+}
+
+// HasPermission reports whether any of granted covers required
+func HasPermission(granted []string, required string) bool {
+	for _, p := range granted {
+		if PermissionMatches(p, required) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidPermission checks that p is made of non-empty segments without spaces
+func ValidPermission(p string) bool {
+	if p == "" || strings.ContainsAny(p, " \t\n") {
+		return false
+	}
+	for _, segment := range strings.Split(p, ":") {
+		if segment == "" {
+			return false
+		}
+	}
+	return true
+}
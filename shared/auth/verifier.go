@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Algorithms are the only signing algorithms tokens may use
+var Algorithms = []string{"RS256", "EdDSA"}
+
+// DefaultIssuer is the iss of tokens minted by the Auth service
+const DefaultIssuer = "auth"
+
+// revocationTimeout bounds the Redis lookup of a revocation check
+const revocationTimeout = 2 * time.Second
+
+var (
+	// ErrUnknownKey is returned for tokens signed by a key we don't have
+	ErrUnknownKey = errors.New("unknown signing key")
+	// ErrWrongTokenPurpose is returned when a single-purpose token is used as an access token
+	ErrWrongTokenPurpose = errors.New("token not valid for this purpose")
+)
+
+// VerifierOptions are the checks a service applies on top of the signature
+type VerifierOptions struct {
+	// Issuer is the required iss
+	Issuer string
+	// Audience is the name of this service; it must be listed in aud
+	Audience string
+	// Leeway tolerates clock skew between services on exp, nbf and iat
+	Leeway time.Duration
+	// Revocations, when set, rejects revoked tokens
+	Revocations *Revocations
+}
+
+// VerifierOptionsFromEnv reads JWT_ISSUER (default "auth"), JWT_AUDIENCE
+// (default audience) and JWT_LEEWAY_SECONDS (default 30)
+func VerifierOptionsFromEnv(audience string) VerifierOptions {
+	opts := VerifierOptions{
+		Issuer:   os.Getenv("JWT_ISSUER"),
+		Audience: os.Getenv("JWT_AUDIENCE"),
+		Leeway:   30 * time.Second,
+	}
+	if opts.Issuer == "" {
+		opts.Issuer = DefaultIssuer
+	}
+	if opts.Audience == "" {
+		opts.Audience = audience
+	}
+	if s, err := strconv.Atoi(os.Getenv("JWT_LEEWAY_SECONDS")); err == nil && s >= 0 {
+		opts.Leeway = time.Duration(s) * time.Second
+	}
+	return opts
+}
+
+// Verifier checks access tokens against a KeySource
+type Verifier struct {
+	keys        KeySource
+	parser      *jwt.Parser
+	revocations *Revocations
+}
+
+// NewVerifier returns a verifier trusting the keys of src
+func NewVerifier(src KeySource, opts VerifierOptions) *Verifier {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(Algorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(opts.Leeway),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+	return &Verifier{keys: src, parser: jwt.NewParser(parserOpts...), revocations: opts.Revocations}
+}
+
+// Verify parses and validates an access token. Errors are *TokenError.
+func (v *Verifier) Verify(tokenStr string) (*Claims, error) {
+	if tokenStr == "" {
+		return nil, classify(ErrMissingToken)
+	}
+	claims := &Claims{}
+	if _, err := v.parser.ParseWithClaims(tokenStr, claims, KeyFunc(v.keys)); err != nil {
+		return nil, classify(err)
+	}
+
+	if v.revocations != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), revocationTimeout)
+		defer cancel()
+		if err := v.revocations.Check(ctx, claims); errors.Is(err, ErrTokenRevoked) {
+			return nil, classify(err)
+		} else if err != nil {
+			// Fail closed: a token we can't check might have been revoked
+			return nil, &TokenError{Reason: ReasonUnavailable, Err: err}
+		}
+	}
+	return claims, nil
+}
+
+// VerifyRequest verifies the bearer token of r
+func (v *Verifier) VerifyRequest(r *http.Request) (*Claims, error) {
+	return v.Verify(BearerToken(r))
+}
+
+// KeyFunc returns a jwt.Keyfunc resolving keys from src. The token's algorithm
+// must be the one of the key named by its kid.
+func KeyFunc(src KeySource) jwt.Keyfunc {
+	return func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			return nil, ErrUnknownKey
+		}
+		alg, key, err := src.Key(kid)
+		if err != nil {
+			return nil, err
+		}
+		if t.Method.Alg() != alg {
+			return nil, fmt.Errorf("unexpected signing method %s for key %s", t.Method.Alg(), kid)
+		}
+		return key, nil
+	}
+}
+
+// BearerToken returns the token of an "Authorization: Bearer" header
+func BearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+}
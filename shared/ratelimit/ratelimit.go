@@ -0,0 +1,64 @@
+// Package ratelimit limits requests per user, or per client IP for anonymous
+// requests, with counters kept in Redis.
+package ratelimit
+
+import (
+	"net/http"
+
+	"shared/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/redis/go-redis/v9"
+)
+
+// PerUser returns a gin middleware allowing limit requests per user. It keys
+// on the claims the service's auth middleware stored, so it must run after it
+// on authenticated routes. Requests without claims are counted against their
+// client IP.
+func PerUser(rdb *redis.Client, limit redis_rate.Limit) gin.HandlerFunc {
+	return PerUserIn(rdb, "", limit)
+}
+
+// PerUserIn is PerUser counting in its own bucket, so a route can have a limit
+// apart from the service-wide one
+func PerUserIn(rdb *redis.Client, bucket string, limit redis_rate.Limit) gin.HandlerFunc {
+	limiter := redis_rate.NewLimiter(rdb)
+
+	return func(c *gin.Context) {
+		key := Key(auth.ClaimsFrom(c), c.ClientIP())
+		if bucket != "" {
+			key = bucket + ":" + key
+		}
+		res, err := limiter.Allow(c.Request.Context(), key, limit)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "rate limiter error"})
+			return
+		}
+
+		if res.Allowed == 0 {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":   "Too many requests",
+				"message": "Rate limit exceeded. Try again later.",
+				"retryIn": res.RetryAfter.Seconds(),
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Key is the bucket a request is counted in: the user of its verified claims,
+// else clientIP
+func Key(claims *auth.Claims, clientIP string) string {
+	if claims != nil {
+		if claims.UserID != "" {
+			return "user:" + claims.UserID
+		}
+		if claims.Subject != "" {
+			return "user:" + claims.Subject
+		}
+	}
+	return "ip:" + clientIP
+}
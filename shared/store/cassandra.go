@@ -0,0 +1,114 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// CassandraOptions configures ConnectCassandra
+type CassandraOptions struct {
+	Hosts       []string
+	Port        int
+	Keyspace    string
+	Consistency gocql.Consistency
+	Timeout     time.Duration
+	// CreateKeyspace creates Keyspace (SimpleStrategy) when it does not exist
+	CreateKeyspace    bool
+	ReplicationFactor int
+}
+
+// CassandraOptionsFromEnv reads CASSANDRA_HOST (comma separated, default
+// 127.0.0.1), CASSANDRA_PORT and CASSANDRA_KEYSPACE (default defaultKeyspace)
+func CassandraOptionsFromEnv(defaultKeyspace string) CassandraOptions {
+	opts := CassandraOptions{
+		Keyspace:          os.Getenv("CASSANDRA_KEYSPACE"),
+		Consistency:       gocql.Quorum,
+		Timeout:           10 * time.Second,
+		ReplicationFactor: 1,
+	}
+	for _, host := range strings.Split(os.Getenv("CASSANDRA_HOST"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			opts.Hosts = append(opts.Hosts, host)
+		}
+	}
+	if len(opts.Hosts) == 0 {
+		opts.Hosts = []string{"127.0.0.1"}
+	}
+	if port, err := strconv.Atoi(os.Getenv("CASSANDRA_PORT")); err == nil && port > 0 {
+		opts.Port = port
+	}
+	if opts.Keyspace == "" {
+		opts.Keyspace = defaultKeyspace
+	}
+	return opts
+}
+
+// ConnectCassandra opens a session bound to opts.Keyspace
+func ConnectCassandra(opts CassandraOptions) (*gocql.Session, error) {
+	cluster := gocql.NewCluster(opts.Hosts...)
+	if opts.Port > 0 {
+		cluster.Port = opts.Port
+	}
+	if opts.Timeout > 0 {
+		cluster.Timeout = opts.Timeout
+		cluster.ConnectTimeout = opts.Timeout
+	}
+	cluster.Consistency = opts.Consistency
+
+	if opts.CreateKeyspace {
+		if err := createKeyspace(cluster, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	cluster.Keyspace = opts.Keyspace
+	return cluster.CreateSession()
+}
+
+func createKeyspace(cluster *gocql.ClusterConfig, opts CassandraOptions) error {
+	session, err := cluster.CreateSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	rf := opts.ReplicationFactor
+	if rf <= 0 {
+		rf = 1
+	}
+	query := fmt.Sprintf(`CREATE KEYSPACE IF NOT EXISTS %s WITH replication = {'class': 'SimpleStrategy', 'replication_factor': %d}`,
+		opts.Keyspace, rf)
+	return session.Query(query).Exec()
+}
+
+// EnsureColumns adds the given (name, type) columns to table when they are
+// missing and returns the names of the columns it added
+func EnsureColumns(session *gocql.Session, keyspace, table string, columns [][2]string) ([]string, error) {
+	existing := make(map[string]bool)
+	iter := session.Query(`SELECT column_name FROM system_schema.columns WHERE keyspace_name = ? AND table_name = ?`,
+		keyspace, table).Iter()
+	var name string
+	for iter.Scan(&name) {
+		existing[name] = true
+	}
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("reading schema of %s: %w", table, err)
+	}
+
+	var added []string
+	for _, col := range columns {
+		if existing[col[0]] {
+			continue
+		}
+		if err := session.Query(fmt.Sprintf(`ALTER TABLE %s ADD %s %s`, table, col[0], col[1])).Exec(); err != nil {
+			return added, fmt.Errorf("adding column %s.%s: %w", table, col[0], err)
+		}
+		added = append(added, col[0])
+	}
+	return added, nil
+}
@@ -0,0 +1,50 @@
+// Package store opens the Redis and Cassandra connections every service uses.
+package store
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisOptions configures ConnectRedis
+type RedisOptions struct {
+	Addr        string
+	Password    string
+	DB          int
+	DialTimeout time.Duration
+}
+
+// RedisOptionsFromEnv reads REDIS_HOST (default localhost:6379), REDIS_PASSWORD and REDIS_DB
+func RedisOptionsFromEnv() RedisOptions {
+	opts := RedisOptions{
+		Addr:        os.Getenv("REDIS_HOST"),
+		Password:    os.Getenv("REDIS_PASSWORD"),
+		DialTimeout: 5 * time.Second,
+	}
+	if opts.Addr == "" {
+		opts.Addr = "localhost:6379"
+	}
+	if db, err := strconv.Atoi(os.Getenv("REDIS_DB")); err == nil && db >= 0 {
+		opts.DB = db
+	}
+	return opts
+}
+
+// ConnectRedis opens a client and checks it with a PING
+func ConnectRedis(ctx context.Context, opts RedisOptions) (*redis.Client, error) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:        opts.Addr,
+		Password:    opts.Password,
+		DB:          opts.DB,
+		DialTimeout: opts.DialTimeout,
+	})
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		rdb.Close()
+		return nil, err
+	}
+	return rdb, nil
+}
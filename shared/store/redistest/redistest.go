@@ -0,0 +1,189 @@
+// Package redistest runs an in-memory Redis for tests. It speaks just enough
+// RESP for the services: PING, SET with EX/PX, GET, MGET, GETDEL, DEL and
+// MULTI/EXEC. Expiry is enforced.
+package redistest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Server is a fake Redis listening on a local port
+type Server struct {
+	mu       sync.Mutex
+	values   map[string]string
+	expires  map[string]time.Time
+	ttls     map[string]string
+	listener net.Listener
+}
+
+// NewServer starts a Server that stops when the test ends
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("redistest: %v", err)
+	}
+	s := &Server{
+		values:   map[string]string{},
+		expires:  map[string]time.Time{},
+		ttls:     map[string]string{},
+		listener: listener,
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Client returns a client of the server, closed when the test ends
+func (s *Server) Client(t testing.TB) *redis.Client {
+	rdb := redis.NewClient(&redis.Options{Addr: s.listener.Addr().String(), Protocol: 2, DisableIdentity: true, MaxRetries: -1})
+	t.Cleanup(func() { rdb.Close() })
+	return rdb
+}
+
+// TTL returns the expiry the last SET of key asked for, as sent ("PX 1500"),
+// or "" when it had none
+func (s *Server) TTL(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ttls[key]
+}
+
+// Close stops the server, so clients see it as down
+func (s *Server) Close() {
+	s.listener.Close()
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	var queued []string // replies of a MULTI block, sent on EXEC
+	inMulti := false
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(args[0])
+		var reply string
+		switch {
+		case command == "MULTI":
+			inMulti, queued, reply = true, nil, "+OK\r\n"
+		case command == "EXEC":
+			reply = fmt.Sprintf("*%d\r\n%s", len(queued), strings.Join(queued, ""))
+			inMulti, queued = false, nil
+		case inMulti:
+			queued = append(queued, s.apply(args))
+			reply = "+QUEUED\r\n"
+		default:
+			reply = s.apply(args)
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) apply(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, at := range s.expires {
+		if time.Now().After(at) {
+			s.remove(key)
+		}
+	}
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "SET":
+		s.values[args[1]] = args[2]
+		delete(s.expires, args[1])
+		delete(s.ttls, args[1])
+		if len(args) == 5 {
+			n, _ := strconv.Atoi(args[4])
+			unit := time.Second
+			if strings.EqualFold(args[3], "px") {
+				unit = time.Millisecond
+			}
+			s.expires[args[1]] = time.Now().Add(time.Duration(n) * unit)
+			s.ttls[args[1]] = strings.ToUpper(args[3]) + " " + args[4]
+		}
+		return "+OK\r\n"
+	case "GET":
+		return s.bulk(args[1])
+	case "MGET":
+		reply := fmt.Sprintf("*%d\r\n", len(args)-1)
+		for _, key := range args[1:] {
+			reply += s.bulk(key)
+		}
+		return reply
+	case "GETDEL":
+		reply := s.bulk(args[1])
+		s.remove(args[1])
+		return reply
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := s.values[key]; ok {
+				deleted++
+				s.remove(key)
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func (s *Server) remove(key string) {
+	delete(s.values, key)
+	delete(s.expires, key)
+	delete(s.ttls, key)
+}
+
+func (s *Server) bulk(key string) string {
+	v, ok := s.values[key]
+	if !ok {
+		return "$-1\r\n"
+	}
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := r.ReadString('\n'); err != nil { // $<len>
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
@@ -0,0 +1,42 @@
+package test
+
+import (
+	"testing"
+
+	"shared/auth"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissionMatches(t *testing.T) {
+	cases := []struct {
+		granted, required string
+		want              bool
+	}{
+		{"*", "camera:view:channel2", true},
+		{"camera:*", "camera:view:channel2", true},
+		{"camera:view:*", "camera:view:channel2", true},
+		{"camera:view:channel2", "camera:view:channel2", true},
+		{"camera:*:channel2", "camera:view:channel2", true},
+		{"camera:view:channel1", "camera:view:channel2", false},
+		{"camera:view", "camera:view:channel2", false},
+		{"camera:view:*", "camera:view", false},
+		{"chat:moderate", "chat:write", false},
+		{"camera:*", "chat:read", false},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, auth.PermissionMatches(tc.granted, tc.required), "%s vs %s", tc.granted, tc.required)
+	}
+}
+
+func TestHasPermissionAndValidation(t *testing.T) {
+	granted := []string{"chat:read", "camera:view:*"}
+	assert.True(t, auth.HasPermission(granted, "camera:view:channel4"))
+	assert.False(t, auth.HasPermission(granted, "chat:moderate"))
+	assert.False(t, auth.HasPermission(nil, "chat:read"))
+
+	assert.True(t, auth.ValidPermission("chat:moderate"))
+	assert.False(t, auth.ValidPermission(""))
+	assert.False(t, auth.ValidPermission("chat::read"))
+	assert.False(t, auth.ValidPermission("chat read"))
+}
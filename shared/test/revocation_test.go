@@ -0,0 +1,117 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"shared/auth"
+	"shared/store/redistest"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func revokingVerifier(issuer *stubIssuer, revocations *auth.Revocations) *auth.Verifier {
+	return auth.NewVerifier(auth.NewJWKS(issuer.server.URL, auth.JWKSOptions{}), auth.VerifierOptions{
+		Issuer:      "auth",
+		Audience:    "camera",
+		Leeway:      30 * time.Second,
+		Revocations: revocations,
+	})
+}
+
+func TestRevokedTokensAreRejected(t *testing.T) {
+	issuer := newStubIssuer(t)
+	issuer.addEd25519(t, "ed-1")
+	redisServer := redistest.NewServer(t)
+	revocations := auth.NewRevocations(redisServer.Client(t))
+	verifier := revokingVerifier(issuer, revocations)
+	ctx := t.Context()
+
+	claims := accessClaims()
+	claims.SessionID = "session-1"
+	token := issuer.sign(t, "ed-1", claims)
+	_, err := verifier.Verify(token)
+	require.NoError(t, err)
+
+	other := accessClaims()
+	other.ID = "jti-2"
+	otherToken := issuer.sign(t, "ed-1", other)
+
+	require.NoError(t, revocations.RevokeToken(ctx, claims))
+	_, err = verifier.Verify(token)
+	assert.Equal(t, auth.ReasonRevoked, auth.ReasonOf(err))
+	var ttl int
+	_, err = fmt.Sscanf(redisServer.TTL("revoked:jti:jti-1"), "PX %d", &ttl)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute.Milliseconds(), ttl, 5000, "lives as long as the token")
+	_, err = verifier.Verify(otherToken)
+	assert.NoError(t, err, "other tokens of the user stay valid")
+
+	claims.ID = "jti-3"
+	require.NoError(t, revocations.RevokeSession(ctx, "session-1", time.Minute))
+	_, err = verifier.Verify(issuer.sign(t, "ed-1", claims))
+	assert.Equal(t, auth.ReasonRevoked, auth.ReasonOf(err), "session revoked")
+}
+
+func TestRevokeUserOnlyAffectsEarlierTokens(t *testing.T) {
+	issuer := newStubIssuer(t)
+	issuer.addEd25519(t, "ed-1")
+	revocations := auth.NewRevocations(redistest.NewServer(t).Client(t))
+	verifier := revokingVerifier(issuer, revocations)
+
+	before := accessClaims()
+	before.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	require.NoError(t, revocations.RevokeUser(t.Context(), "user-1", time.Minute))
+
+	_, err := verifier.Verify(issuer.sign(t, "ed-1", before))
+	assert.Equal(t, auth.ReasonRevoked, auth.ReasonOf(err))
+
+	after := accessClaims()
+	after.IssuedAt = jwt.NewNumericDate(time.Now().Add(2 * time.Second))
+	_, err = verifier.Verify(issuer.sign(t, "ed-1", after))
+	assert.NoError(t, err)
+}
+
+func TestRevokeUserKeepsTokensOfTheSameSecond(t *testing.T) {
+	precision := jwt.TimePrecision
+	jwt.TimePrecision = time.Millisecond
+	defer func() { jwt.TimePrecision = precision }()
+
+	issuer := newStubIssuer(t)
+	issuer.addEd25519(t, "ed-1")
+	revocations := auth.NewRevocations(redistest.NewServer(t).Client(t))
+	verifier := revokingVerifier(issuer, revocations)
+
+	// Start early in a second so the revocation and both tokens share it
+	if now := time.Now(); now.Nanosecond() > 800*int(time.Millisecond) {
+		time.Sleep(now.Truncate(time.Second).Add(time.Second).Sub(now))
+	}
+	revoked := accessClaims()
+	revoked.IssuedAt = jwt.NewNumericDate(time.Now())
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, revocations.RevokeUser(t.Context(), "user-1", time.Minute))
+	time.Sleep(5 * time.Millisecond)
+	refreshed := accessClaims()
+	refreshed.ID = "jti-2"
+	refreshed.IssuedAt = jwt.NewNumericDate(time.Now())
+	require.Equal(t, revoked.IssuedAt.Unix(), refreshed.IssuedAt.Unix())
+
+	_, err := verifier.Verify(issuer.sign(t, "ed-1", revoked))
+	assert.Equal(t, auth.ReasonRevoked, auth.ReasonOf(err), "issued just before the revocation")
+	_, err = verifier.Verify(issuer.sign(t, "ed-1", refreshed))
+	assert.NoError(t, err, "refreshed just after the revocation")
+}
+
+func TestRevocationCheckFailsClosed(t *testing.T) {
+	issuer := newStubIssuer(t)
+	issuer.addEd25519(t, "ed-1")
+	redisServer := redistest.NewServer(t)
+	verifier := revokingVerifier(issuer, auth.NewRevocations(redisServer.Client(t)))
+	redisServer.Close()
+
+	_, err := verifier.Verify(issuer.sign(t, "ed-1", accessClaims()))
+	assert.Equal(t, auth.ReasonUnavailable, auth.ReasonOf(err))
+}
@@ -0,0 +1,52 @@
+package test
+
+import (
+	"testing"
+
+	"shared/auth"
+	"shared/ratelimit"
+	"shared/store"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCassandraOptionsFromEnv(t *testing.T) {
+	t.Setenv("CASSANDRA_HOST", "")
+	t.Setenv("CASSANDRA_PORT", "")
+	t.Setenv("CASSANDRA_KEYSPACE", "")
+	opts := store.CassandraOptionsFromEnv("chat")
+	assert.Equal(t, []string{"127.0.0.1"}, opts.Hosts)
+	assert.Equal(t, "chat", opts.Keyspace)
+	assert.Equal(t, 0, opts.Port)
+	assert.Equal(t, gocql.Quorum, opts.Consistency)
+
+	t.Setenv("CASSANDRA_HOST", "cass-1, cass-2")
+	t.Setenv("CASSANDRA_PORT", "9043")
+	t.Setenv("CASSANDRA_KEYSPACE", "auth")
+	opts = store.CassandraOptionsFromEnv("chat")
+	assert.Equal(t, []string{"cass-1", "cass-2"}, opts.Hosts)
+	assert.Equal(t, 9043, opts.Port)
+	assert.Equal(t, "auth", opts.Keyspace)
+}
+
+func TestRedisOptionsFromEnv(t *testing.T) {
+	t.Setenv("REDIS_HOST", "")
+	t.Setenv("REDIS_DB", "")
+	assert.Equal(t, "localhost:6379", store.RedisOptionsFromEnv().Addr)
+
+	t.Setenv("REDIS_HOST", "redis:6380")
+	t.Setenv("REDIS_DB", "2")
+	opts := store.RedisOptionsFromEnv()
+	assert.Equal(t, "redis:6380", opts.Addr)
+	assert.Equal(t, 2, opts.DB)
+}
+
+func TestRateLimitKey(t *testing.T) {
+	assert.Equal(t, "ip:10.0.0.1", ratelimit.Key(nil, "10.0.0.1"))
+	assert.Equal(t, "user:user-1", ratelimit.Key(accessClaims(), "10.0.0.1"))
+
+	claims := &auth.Claims{}
+	claims.Subject = "user-2"
+	assert.Equal(t, "user:user-2", ratelimit.Key(claims, "10.0.0.1"))
+}
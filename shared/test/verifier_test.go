@@ -0,0 +1,267 @@
+package test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"shared/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubIssuer signs tokens and serves its public keys like the Auth service
+type stubIssuer struct {
+	mu      sync.Mutex
+	ed      map[string]ed25519.PrivateKey
+	rsa     map[string]*rsa.PrivateKey
+	fetches int
+	server  *httptest.Server
+}
+
+func newStubIssuer(t *testing.T) *stubIssuer {
+	s := &stubIssuer{ed: map[string]ed25519.PrivateKey{}, rsa: map[string]*rsa.PrivateKey{}}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.fetches++
+		keys := []map[string]string{}
+		for kid, key := range s.ed {
+			keys = append(keys, map[string]string{"kid": kid, "kty": "OKP", "crv": "Ed25519", "alg": "EdDSA",
+				"x": base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey))})
+		}
+		for kid, key := range s.rsa {
+			keys = append(keys, map[string]string{"kid": kid, "kty": "RSA", "alg": "RS256",
+				"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *stubIssuer) addEd25519(t *testing.T, kid string) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	s.mu.Lock()
+	s.ed[kid] = key
+	s.mu.Unlock()
+}
+
+func (s *stubIssuer) addRSA(t *testing.T, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	s.mu.Lock()
+	s.rsa[kid] = key
+	s.mu.Unlock()
+}
+
+func (s *stubIssuer) sign(t *testing.T, kid string, claims jwt.Claims) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var token *jwt.Token
+	var key interface{}
+	if k, ok := s.ed[kid]; ok {
+		token, key = jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims), k
+	} else {
+		token, key = jwt.NewWithClaims(jwt.SigningMethodRS256, claims), s.rsa[kid]
+	}
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func accessClaims() *auth.Claims {
+	now := time.Now()
+	return &auth.Claims{
+		UserID:      "user-1",
+		Role:        "staff",
+		Email:       "staff@example.com",
+		Permissions: []string{"chat:read", "camera:view:*"},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "auth",
+			Subject:   "user-1",
+			Audience:  jwt.ClaimStrings{"auth", "camera"},
+			ID:        "jti-1",
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	}
+}
+
+// cameraVerifier verifies like the camera service
+func cameraVerifier(issuer *stubIssuer, opts auth.JWKSOptions) *auth.Verifier {
+	return auth.NewVerifier(auth.NewJWKS(issuer.server.URL, opts), auth.VerifierOptions{
+		Issuer:   "auth",
+		Audience: "camera",
+		Leeway:   30 * time.Second,
+	})
+}
+
+func TestVerifierAcceptsPublishedKeys(t *testing.T) {
+	issuer := newStubIssuer(t)
+	issuer.addEd25519(t, "ed-1")
+	issuer.addRSA(t, "rsa-1")
+	verifier := cameraVerifier(issuer, auth.JWKSOptions{})
+
+	for _, kid := range []string{"ed-1", "rsa-1"} {
+		claims, err := verifier.Verify(issuer.sign(t, kid, accessClaims()))
+		require.NoError(t, err, kid)
+		assert.Equal(t, "user-1", claims.UserID)
+		assert.True(t, claims.Can("camera:view:channel2"))
+		assert.False(t, claims.Can("chat:moderate"))
+	}
+	assert.Equal(t, 1, issuer.fetches, "keys are cached")
+}
+
+func TestVerifierPicksUpRotatedKeys(t *testing.T) {
+	issuer := newStubIssuer(t)
+	issuer.addEd25519(t, "old")
+	verifier := cameraVerifier(issuer, auth.JWKSOptions{MinRefresh: time.Nanosecond})
+
+	_, err := verifier.Verify(issuer.sign(t, "old", accessClaims()))
+	require.NoError(t, err)
+
+	issuer.addEd25519(t, "new")
+	_, err = verifier.Verify(issuer.sign(t, "new", accessClaims()))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, issuer.fetches)
+}
+
+func TestVerifierRejectsForgedTokens(t *testing.T) {
+	issuer := newStubIssuer(t)
+	issuer.addRSA(t, "rsa-1")
+	verifier := cameraVerifier(issuer, auth.JWKSOptions{})
+
+	// HS256 keyed with the public modulus (algorithm confusion)
+	hs := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims())
+	hs.Header["kid"] = "rsa-1"
+	forged, _ := hs.SignedString(issuer.rsa["rsa-1"].N.Bytes())
+	_, err := verifier.Verify(forged)
+	assert.Error(t, err)
+
+	// A key the issuer never published
+	other := newStubIssuer(t)
+	other.addEd25519(t, "rsa-1-but-not-really")
+	_, err = verifier.Verify(other.sign(t, "rsa-1-but-not-really", accessClaims()))
+	assert.ErrorIs(t, err, auth.ErrUnknownKey)
+	assert.Equal(t, auth.ReasonUnknownKey, auth.ReasonOf(err))
+
+	// Single-purpose tokens are not access tokens
+	purpose := accessClaims()
+	purpose.Purpose = "email_verify"
+	_, err = verifier.Verify(issuer.sign(t, "rsa-1", purpose))
+	assert.ErrorIs(t, err, auth.ErrWrongTokenPurpose)
+	assert.Equal(t, auth.ReasonWrongPurpose, auth.ReasonOf(err))
+}
+
+func TestVerifierReasons(t *testing.T) {
+	issuer := newStubIssuer(t)
+	issuer.addEd25519(t, "ed-1")
+	verifier := cameraVerifier(issuer, auth.JWKSOptions{})
+
+	cases := map[auth.Reason]func(c *auth.Claims){
+		auth.ReasonExpired:         func(c *auth.Claims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) },
+		auth.ReasonNotYetValid:     func(c *auth.Claims) { c.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Minute)) },
+		auth.ReasonInvalidIssuer:   func(c *auth.Claims) { c.Issuer = "someone-else" },
+		auth.ReasonInvalidAudience: func(c *auth.Claims) { c.Audience = jwt.ClaimStrings{"feedback"} },
+		auth.ReasonInvalidClaims:   func(c *auth.Claims) { c.Email = "" },
+	}
+	for want, mutate := range cases {
+		claims := accessClaims()
+		mutate(claims)
+		_, err := verifier.Verify(issuer.sign(t, "ed-1", claims))
+		assert.Equal(t, want, auth.ReasonOf(err), err)
+
+		var tokenErr *auth.TokenError
+		if assert.ErrorAs(t, err, &tokenErr) {
+			assert.NotEmpty(t, tokenErr.Message())
+		}
+	}
+
+	_, err := verifier.Verify("")
+	assert.Equal(t, auth.ReasonMissing, auth.ReasonOf(err))
+	_, err = verifier.Verify("not.a.jwt")
+	assert.Equal(t, auth.ReasonMalformed, auth.ReasonOf(err))
+
+	// A few seconds of clock skew are tolerated
+	skewed := accessClaims()
+	skewed.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Second))
+	skewed.IssuedAt = jwt.NewNumericDate(time.Now().Add(10 * time.Second))
+	_, err = verifier.Verify(issuer.sign(t, "ed-1", skewed))
+	assert.NoError(t, err)
+}
+
+func TestVerifierOptionsFromEnv(t *testing.T) {
+	t.Setenv("JWT_ISSUER", "")
+	t.Setenv("JWT_AUDIENCE", "")
+	t.Setenv("JWT_LEEWAY_SECONDS", "")
+	opts := auth.VerifierOptionsFromEnv("camera")
+	assert.Equal(t, auth.VerifierOptions{Issuer: "auth", Audience: "camera", Leeway: 30 * time.Second}, opts)
+
+	t.Setenv("JWT_ISSUER", "https://auth.example.com")
+	t.Setenv("JWT_AUDIENCE", "camera-eu")
+	t.Setenv("JWT_LEEWAY_SECONDS", "5")
+	opts = auth.VerifierOptionsFromEnv("camera")
+	assert.Equal(t, auth.VerifierOptions{Issuer: "https://auth.example.com", Audience: "camera-eu", Leeway: 5 * time.Second}, opts)
+}
+
+func TestJWKSLookupsDoNotWaitOnAFetch(t *testing.T) {
+	issuer := newStubIssuer(t)
+	issuer.addEd25519(t, "known")
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			<-release // Auth hangs after the first fetch
+		}
+		issuer.server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	defer close(release)
+	keys := auth.NewJWKS(server.URL, auth.JWKSOptions{MinRefresh: time.Nanosecond})
+
+	_, _, err := keys.Key("known")
+	require.NoError(t, err)
+
+	// Two lookups of a new kid share one fetch, which hangs
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			keys.Key("new")
+		}()
+	}
+	require.Eventually(t, func() bool { return fetches.Load() == 2 }, time.Second, time.Millisecond)
+
+	found := make(chan error)
+	go func() {
+		_, _, err := keys.Key("known")
+		found <- err
+	}()
+	select {
+	case err := <-found:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("a known key waited for the fetch")
+	}
+
+	release <- struct{}{}
+	wg.Wait()
+	assert.Equal(t, int32(2), fetches.Load())
+}